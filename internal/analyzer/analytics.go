@@ -1,17 +1,24 @@
 package analyzer
 
 import (
-	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gotd/td/tg"
 )
 
+const (
+	topEmojiLimit   = 10
+	topHashtagLimit = 10
+)
+
 type Message struct {
-	Text     string    `json:"text"`
-	Views    int       `json:"views"`
-	Comments int       `json:"comments"`
-	Date     time.Time `json:"date"`
+	Text           string    `json:"text"`
+	Views          int       `json:"views"`
+	Comments       int       `json:"comments"`
+	Date           time.Time `json:"date"`
+	EngagementRate float64   `json:"engagement_rate"`
 }
 
 type OverallMetrics struct {
@@ -23,9 +30,9 @@ type OverallMetrics struct {
 }
 
 func (m *OverallMetrics) UpdateMetrics(msg *tg.Message) {
-	_, totalReactions := countNumOfReactions(msg.Reactions)
+	reactions := countNumOfReactions(msg.Reactions)
 	m.TotalViews += msg.Views
-	m.TotalReactions += totalReactions
+	m.TotalReactions += reactions.total
 	m.TotalComments += msg.Replies.Replies
 	m.TotalPosts += 1
 	_, ok := msg.FwdFrom.GetFromID()
@@ -35,21 +42,82 @@ func (m *OverallMetrics) UpdateMetrics(msg *tg.Message) {
 	m.TotalForwards += 1
 }
 
+// postEngagement is the number of reactions, comments and shares of a post.
+func postEngagement(msg *tg.Message) int {
+	reactions := countNumOfReactions(msg.Reactions)
+	return reactions.total + msg.Replies.Replies + msg.Forwards
+}
+
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+}
+
+func newDistribution(values []float64) Distribution {
+	return Distribution{
+		Mean:   mean(values),
+		Median: median(values),
+		P90:    percentile(values, 90),
+	}
+}
+
+type EngagementMetrics struct {
+	TotalEngagements        int          `json:"total_engagements"`
+	TotalShares             int          `json:"total_shares"`
+	EngagementRate          float64      `json:"engagement_rate"`
+	PostEngagementRate      Distribution `json:"post_engagement_rate"`
+	ReactionsPer1kViews     float64      `json:"reactions_per_1k_views"`
+	Subscribers             int          `json:"subscribers"`
+	EngagementPerSubscriber float64      `json:"engagement_per_subscriber"`
+	PostEngagementPerSub    Distribution `json:"post_engagement_per_subscriber"`
+	postEngagements         []int
+	postRates               []float64
+}
+
+func (e *EngagementMetrics) UpdateEngagement(msg *tg.Message) {
+	engagement := postEngagement(msg)
+	e.TotalEngagements += engagement
+	e.TotalShares += msg.Forwards
+	e.postEngagements = append(e.postEngagements, engagement)
+	if msg.Views != 0 {
+		e.postRates = append(e.postRates, ratio(engagement, msg.Views))
+	}
+}
+
+// TimeTrends buckets posts by their local time in the window's timezone. The
+// heatmaps are indexed by weekday (Sunday first) and then by hour.
 type TimeTrends struct {
 	ViewsByMonth         map[string]int   `json:"views_by_month"`
 	PostsByDay           map[string][]int `json:"posts_by_day"`
 	PostsByMonth         map[string]int   `json:"posts_by_month"`
 	PostsByHour          map[int]int      `json:"posts_by_hour"`
 	LongestPostingStreak int              `json:"longest_posting_streak"`
+	PostsHeatmap         [7][24]int       `json:"posts_heatmap"`
+	AverageViewsHeatmap  [7][24]float64   `json:"average_views_heatmap"`
+	BestPerformingHour   int              `json:"best_performing_hour"`
+	BestPerformingDay    string           `json:"best_performing_day"`
+	AverageGapHours      float64          `json:"average_gap_hours"`
+	Burstiness           float64          `json:"burstiness"`
+	WeeklyConsistency    float64          `json:"weekly_consistency"`
+
+	location     *time.Location
+	viewsHeatmap [7][24]int
+	postDates    []int
 }
 
 func (t *TimeTrends) UpdateTrends(mm *tg.Message) {
 	dateTime := getDateTime(mm.Date)
-	month := dateTime.Month().String()
-	monthKey := fmt.Sprintf("%d-%s", dateTime.Year(), month)
+	if t.location != nil {
+		dateTime = dateTime.In(t.location)
+	}
+	monthKey := MonthKey(dateTime)
 	t.PostsByMonth[monthKey] += 1
 	t.ViewsByMonth[monthKey] += mm.Views
 	t.PostsByHour[dateTime.Hour()] += 1
+	t.PostsHeatmap[dateTime.Weekday()][dateTime.Hour()] += 1
+	t.viewsHeatmap[dateTime.Weekday()][dateTime.Hour()] += mm.Views
+	t.postDates = append(t.postDates, mm.Date)
 	tt := time.Date(dateTime.Year(), dateTime.Month()+1, 1, 0, 0, 0, 0, time.UTC)
 	if len(t.PostsByDay[monthKey]) == 0 {
 		lastDay := tt.AddDate(0, 0, -1)
@@ -64,17 +132,48 @@ type ForwardSource struct {
 	ForwardsCount int    `json:"forwards_count"`
 	Profile       string `json:"profile"`
 }
+type CustomReaction struct {
+	DocumentID int64  `json:"document_id,string"`
+	Alt        string `json:"alt"`
+	StickerSet string `json:"sticker_set"`
+	SetName    string `json:"sticker_set_name"`
+	Count      int    `json:"count"`
+	Thumbnail  string `json:"thumbnail"`
+}
+
+type PaidReactions struct {
+	TotalStars       int `json:"total_stars"`
+	PostsWithStars   int `json:"posts_with_stars"`
+	MostStarredID    int `json:"most_starred_id"`
+	MostStarredCount int `json:"most_starred_count"`
+}
+
+func (p *PaidReactions) UpdatePaidReactions(msg *tg.Message, stars int) {
+	if stars == 0 {
+		return
+	}
+	p.TotalStars += stars
+	p.PostsWithStars += 1
+	if p.MostStarredCount < stars {
+		p.MostStarredID = msg.ID
+		p.MostStarredCount = stars
+	}
+}
+
 type TopPosts struct {
-	MostViewedID         int            `json:"most_viewed_id"`
-	MostViewed           Message        `json:"most_viewed"`
-	MostCommented        Message        `json:"most_commented"`
-	MostViewedCount      int            `json:"most_viewed_count"`
-	MostCommentedID      int            `json:"most_commented_id"`
-	MostCommentedCount   int            `json:"most_commented_count"`
-	ForwardsBySource     map[int]int    `json:"-"`
-	MostForwardedSource  ForwardSource  `json:"most_forwarded_source"`
-	MostForwardedChannel *tg.Channel    `json:"-"`
-	ReactionsByType      map[string]int `json:"reactions_by_type"`
+	MostViewedID              int              `json:"most_viewed_id"`
+	MostViewed                Message          `json:"most_viewed"`
+	MostCommented             Message          `json:"most_commented"`
+	MostViewedCount           int              `json:"most_viewed_count"`
+	MostCommentedID           int              `json:"most_commented_id"`
+	MostCommentedCount        int              `json:"most_commented_count"`
+	ForwardsBySource          map[int]int      `json:"-"`
+	MostForwardedSource       ForwardSource    `json:"most_forwarded_source"`
+	MostForwardedChannel      *tg.Channel      `json:"-"`
+	ReactionsByType           map[string]int   `json:"reactions_by_type"`
+	CustomReactionsByDocument map[int64]int    `json:"-"`
+	CustomReactions           []CustomReaction `json:"custom_reactions"`
+	PaidReactions             PaidReactions    `json:"paid_reactions"`
 }
 
 func (tp *TopPosts) GetMostForwardsSource() int {
@@ -89,6 +188,25 @@ func (tp *TopPosts) GetMostForwardsSource() int {
 	return ans
 }
 
+// GetTopCustomReactions returns the most used custom emoji reactions, ordered by
+// count, without their sticker set details resolved.
+func (tp *TopPosts) GetTopCustomReactions(limit int) []CustomReaction {
+	top := make([]CustomReaction, 0, len(tp.CustomReactionsByDocument))
+	for documentID, cnt := range tp.CustomReactionsByDocument {
+		top = append(top, CustomReaction{DocumentID: documentID, Count: cnt})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count == top[j].Count {
+			return top[i].DocumentID < top[j].DocumentID
+		}
+		return top[i].Count > top[j].Count
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
 func (tp *TopPosts) UpdateTopPosts(msg *tg.Message) {
 	if tp.MostViewedID == 0 || tp.MostViewedCount < msg.Views {
 		tp.MostViewedID = msg.ID
@@ -98,8 +216,12 @@ func (tp *TopPosts) UpdateTopPosts(msg *tg.Message) {
 		tp.MostCommentedID = msg.ID
 		tp.MostCommentedCount = msg.Replies.Replies
 	}
-	reactionCounter, _ := (countNumOfReactions(msg.Reactions))
-	tp.ReactionsByType = mergeMaps(tp.ReactionsByType, reactionCounter)
+	reactions := countNumOfReactions(msg.Reactions)
+	tp.ReactionsByType = mergeMaps(tp.ReactionsByType, reactions.byEmoji)
+	for documentID, cnt := range reactions.byCustomEmoji {
+		tp.CustomReactionsByDocument[documentID] += cnt
+	}
+	tp.PaidReactions.UpdatePaidReactions(msg, reactions.paidStars)
 	fromID, ok := msg.FwdFrom.GetFromID()
 	if !ok {
 		return
@@ -130,12 +252,95 @@ func (tp *TopPosts) GetMostForwardedFromChannel(chats []tg.ChatClass, channelID
 	}
 }
 
+type EmojiCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+type EmojiUsage struct {
+	EmojisByType   map[string]int `json:"emojis_by_type"`
+	TopEmojis      []EmojiCount   `json:"top_emojis"`
+	TotalEmojis    int            `json:"total_emojis"`
+	PostsWithEmoji int            `json:"posts_with_emoji"`
+	EmojisPerPost  float64        `json:"emojis_per_post"`
+	PostsWithRatio float64        `json:"posts_with_emoji_ratio"`
+}
+
+func (e *EmojiUsage) UpdateEmojiUsage(msg *tg.Message) {
+	counter, total := countEmojis(msg.Message)
+	if total == 0 {
+		return
+	}
+	e.EmojisByType = mergeMaps(e.EmojisByType, counter)
+	e.TotalEmojis += total
+	e.PostsWithEmoji += 1
+}
+
+type ChannelInfo struct {
+	ID                 int64     `json:"id,string"`
+	Username           string    `json:"username"`
+	Subscribers        int       `json:"subscribers"`
+	Description        string    `json:"description"`
+	CreatedAt          time.Time `json:"created_at"`
+	LinkedChatID       int64     `json:"linked_chat_id,string"`
+	LinkedChatTitle    string    `json:"linked_chat_title"`
+	LinkedChatUsername string    `json:"linked_chat_username"`
+	Verified           bool      `json:"verified"`
+	Scam               bool      `json:"scam"`
+	Fake               bool      `json:"fake"`
+	BoostsLevel        int       `json:"boosts_level"`
+	CapturedAt         time.Time `json:"captured_at"`
+}
+
+type HashtagCount struct {
+	Hashtag string `json:"hashtag"`
+	Count   int    `json:"count"`
+}
+
+type ContentMix struct {
+	PostsByType map[string]int `json:"posts_by_type"`
+	Hashtags    map[string]int `json:"hashtags"`
+	TopHashtags []HashtagCount `json:"top_hashtags"`
+}
+
+func (c *ContentMix) UpdateContent(msg *tg.Message) {
+	c.PostsByType[mediaType(msg)] += 1
+	for _, entity := range msg.Entities {
+		hashtag, ok := entity.(*tg.MessageEntityHashtag)
+		if !ok {
+			continue
+		}
+		tag := strings.ToLower(entityText(msg.Message, hashtag.Offset, hashtag.Length))
+		if tag != "" {
+			c.Hashtags[tag] += 1
+		}
+	}
+}
+
 type Analytics struct {
-	ChannelProfile string         `json:"channel_profile"`
-	ChannelName    string         `json:"channel_name"`
-	Totals         OverallMetrics `json:"totals"`
-	Trends         TimeTrends     `json:"trends"`
-	Highlights     TopPosts       `json:"highlights"`
+	ChannelProfile string            `json:"channel_profile"`
+	ChannelName    string            `json:"channel_name"`
+	Mode           string            `json:"mode"`
+	Window         Window            `json:"window"`
+	Info           ChannelInfo       `json:"channel_info"`
+	Totals         OverallMetrics    `json:"totals"`
+	Trends         TimeTrends        `json:"trends"`
+	Highlights     TopPosts          `json:"highlights"`
+	Emojis         EmojiUsage        `json:"emojis"`
+	Engagement     EngagementMetrics `json:"engagement"`
+	Content        ContentMix        `json:"content"`
+	Edits          EditStats         `json:"edits"`
+	Authors        AuthorBreakdown   `json:"authors"`
+	Polls          PollStats         `json:"polls"`
+	Forwards       ForwardGraph      `json:"forwards"`
+	Sentiment      PostSentiment     `json:"sentiment"`
+	Languages      LanguageBreakdown `json:"languages"`
+	Topics         TopicStats        `json:"topics"`
+	Text           TextStats         `json:"text"`
+	Group          *GroupStats       `json:"group,omitempty"`
+	Discussion     *DiscussionStats  `json:"discussion,omitempty"`
+
+	lastMessageID int
 }
 
 func NewAnalytics(name string) Analytics {
@@ -143,36 +348,102 @@ func NewAnalytics(name string) Analytics {
 	a.ChannelName = name
 	a.Trends.ViewsByMonth = make(map[string]int)
 	a.Highlights.ReactionsByType = make(map[string]int)
+	a.Highlights.CustomReactionsByDocument = make(map[int64]int)
 	a.Trends.PostsByDay = make(map[string][]int)
 	a.Trends.PostsByMonth = make(map[string]int)
 	a.Highlights.ForwardsBySource = make(map[int]int)
 	a.Trends.PostsByHour = make(map[int]int)
+	a.Emojis.EmojisByType = make(map[string]int)
+	a.Content.PostsByType = make(map[string]int)
+	a.Content.Hashtags = make(map[string]int)
+	a.Edits.DeletedPostsByMonth = make(map[string]int)
+	a.Edits.datesByID = make(map[int]int)
+	a.Authors.byAuthor = make(map[string]*AuthorStats)
+	a.Forwards = NewForwardGraph()
+	a.Sentiment.AverageByMonth = make(map[string]float64)
+	a.Sentiment.scoreByMonth = make(map[string]float64)
+	a.Sentiment.postsByMonth = make(map[string]int)
+	a.Languages = NewLanguageBreakdown()
+	a.Text = NewTextStats()
 	return a
 }
-func (a *Analytics) updateFromChannelMessages(m *tg.MessagesChannelMessages) int {
+
+// SetWindow sets the crawl window and buckets the trends in its timezone.
+func (a *Analytics) SetWindow(window Window) {
+	a.Window = window
+	a.Trends.location = window.Location()
+	a.Edits.location = window.Location()
+	a.Authors.location = window.Location()
+	a.Sentiment.location = window.Location()
+	a.Languages.location = window.Location()
+}
+
+func (a *Analytics) updateFromChannelMessages(m tg.ModifiedMessagesMessages) int {
 	if m == nil {
 		return 0
 	}
+	// Resolve forward sources after the batch is counted, including when the
+	// window start is reached midway
+	defer a.Forwards.UpdatePeers(m.GetChats(), m.GetUsers())
 	offSet := 0
-	startDate := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
-	minDateUnix := int(startDate.Unix())
-	for i, msg := range m.Messages {
+	minDateUnix := a.Window.startUnix()
+	maxDateUnix := a.Window.endUnix()
+	for _, msg := range m.GetMessages() {
+		notEmpty, ok := msg.AsNotEmpty()
+		if !ok || a.seenMessage(notEmpty.GetID()) {
+			continue
+		}
+		if notEmpty.GetDate() > minDateUnix && notEmpty.GetDate() < maxDateUnix {
+			a.Edits.UpdateMessageID(notEmpty.GetID(), notEmpty.GetDate())
+		}
 		mm, ok := msg.(*tg.Message)
-		if !ok || i == 0 {
+		if !ok {
 			continue
 		}
 		offSet = mm.Date
 		if mm.Date <= minDateUnix {
 			return minDateUnix
 		}
-		a.Highlights.UpdateTopPosts(mm)
-		a.Totals.UpdateMetrics(mm)
-		a.Trends.UpdateTrends(mm)
+		if mm.Date >= maxDateUnix {
+			continue
+		}
+		a.updateFromMessage(mm)
+		if a.Discussion != nil {
+			a.Discussion.UpdateCandidates(mm)
+		}
 	}
-	channelID := a.Highlights.GetMostForwardsSource()
-	a.Highlights.GetMostForwardedFromChannel(m.Chats, channelID)
 	return offSet
 }
+
+// seenMessage reports whether the message was already handled on a previous
+// page. History is paged by date, so a page can start with the last message of
+// the one before it, history comes newest first so IDs only go down.
+func (a *Analytics) seenMessage(id int) bool {
+	if a.lastMessageID != 0 && id >= a.lastMessageID {
+		return true
+	}
+	a.lastMessageID = id
+	return false
+}
+
+// updateFromMessage feeds a post into every metric shared by channels and
+// groups.
+func (a *Analytics) updateFromMessage(mm *tg.Message) {
+	a.Highlights.UpdateTopPosts(mm)
+	a.Totals.UpdateMetrics(mm)
+	a.Trends.UpdateTrends(mm)
+	a.Emojis.UpdateEmojiUsage(mm)
+	a.Engagement.UpdateEngagement(mm)
+	a.Content.UpdateContent(mm)
+	a.Edits.UpdateEdits(mm)
+	a.Authors.UpdateAuthors(mm)
+	a.Polls.UpdatePolls(mm)
+	a.Forwards.UpdateForwards(mm)
+	a.Sentiment.UpdateSentiment(mm)
+	a.Languages.UpdateLanguages(mm)
+	a.Topics.UpdateTopics(mm)
+	a.Text.UpdateText(mm)
+}
 func (a *Analytics) GetLongestStreak() {
 	array := make([]int, 0)
 	for _, m := range a.Trends.PostsByDay {
@@ -193,3 +464,53 @@ func (a *Analytics) GetLongestStreak() {
 	}
 	a.Trends.LongestPostingStreak = current
 }
+
+func (a *Analytics) GetEmojiStats() {
+	if a.Totals.TotalPosts != 0 {
+		a.Emojis.EmojisPerPost = float64(a.Emojis.TotalEmojis) / float64(a.Totals.TotalPosts)
+		a.Emojis.PostsWithRatio = float64(a.Emojis.PostsWithEmoji) / float64(a.Totals.TotalPosts)
+	}
+	top := make([]EmojiCount, 0, topEmojiLimit)
+	for _, emoji := range rankKeys(a.Emojis.EmojisByType, topEmojiLimit) {
+		top = append(top, EmojiCount{Emoji: emoji, Count: a.Emojis.EmojisByType[emoji]})
+	}
+	a.Emojis.TopEmojis = top
+}
+
+func (a *Analytics) GetTopHashtags() {
+	top := make([]HashtagCount, 0, topHashtagLimit)
+	for _, tag := range rankKeys(a.Content.Hashtags, topHashtagLimit) {
+		top = append(top, HashtagCount{Hashtag: tag, Count: a.Content.Hashtags[tag]})
+	}
+	a.Content.TopHashtags = top
+}
+
+// BestHour returns the hour with the most posts, or -1 when nothing was posted.
+func (t *TimeTrends) BestHour() int {
+	best, bestCount := -1, 0
+	for hour := 0; hour < 24; hour++ {
+		if t.PostsByHour[hour] > bestCount {
+			best, bestCount = hour, t.PostsByHour[hour]
+		}
+	}
+	return best
+}
+
+// GetEngagementStats normalizes the engagement totals by views and, when the
+// subscriber count is known, by subscribers.
+func (a *Analytics) GetEngagementStats(subscribers int) {
+	e := &a.Engagement
+	e.EngagementRate = ratio(e.TotalEngagements, a.Totals.TotalViews)
+	e.ReactionsPer1kViews = ratio(a.Totals.TotalReactions*1000, a.Totals.TotalViews)
+	e.PostEngagementRate = newDistribution(e.postRates)
+	e.Subscribers = subscribers
+	if subscribers == 0 {
+		return
+	}
+	e.EngagementPerSubscriber = ratio(e.TotalEngagements, subscribers)
+	perSubscriber := make([]float64, 0, len(e.postEngagements))
+	for _, engagement := range e.postEngagements {
+		perSubscriber = append(perSubscriber, ratio(engagement, subscribers))
+	}
+	e.PostEngagementPerSub = newDistribution(perSubscriber)
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"mime"
 	"net/http"
 	"os"
@@ -12,13 +13,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/telegram/auth"
 	"github.com/gotd/td/telegram/downloader"
 	"github.com/gotd/td/tg"
 	localAuth "github.com/hunderaweke/tg-unwrapped/internal/auth"
 	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
 	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/sentiment"
 	"github.com/hunderaweke/tg-unwrapped/internal/storage"
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -32,8 +33,10 @@ const (
 
 type Analyzer struct {
 	authenticator localAuth.TermAuth
-	client        *telegram.Client
+	client        telegramClient
+	session       *session
 	minioClient   *storage.MinioClient
+	scorer        sentiment.Scorer
 }
 
 func NewAnalyzer(minioClient *storage.MinioClient) (*Analyzer, error) {
@@ -59,33 +62,63 @@ func NewAnalyzer(minioClient *storage.MinioClient) (*Analyzer, error) {
 			"default", sessionPath)
 	}
 
-	client := telegram.NewClient(appID, appHash, telegram.Options{
-		SessionStorage: &telegram.FileSessionStorage{Path: sessionPath},
+	session := processSession(sessionPath, func() telegramClient {
+		return newTelegramClient(appID, appHash, sessionPath)
 	})
 
 	authenticator := localAuth.NewTermAuth(bufio.NewReader(os.Stdin))
 
+	// Custom lexicons extend the embedded ones, e.g. with domain vocabulary
+	lexiconPaths := make([]string, 0)
+	if paths := os.Getenv("SENTIMENT_LEXICONS"); paths != "" {
+		lexiconPaths = strings.Split(paths, ",")
+	}
+	scorer, err := sentiment.New(lexiconPaths...)
+	if err != nil {
+		return nil, apperrors.NewConfigError("SENTIMENT_LEXICONS", err)
+	}
+
 	logger.Info("Analyzer initialized successfully",
 		"app_id", appID,
 		"session_path", sessionPath)
 
 	return &Analyzer{
-		client:        client,
+		client:        session.client,
+		session:       session,
 		authenticator: authenticator,
 		minioClient:   minioClient,
+		scorer:        scorer,
 	}, nil
 }
 
-func (a *Analyzer) GetChannel(ctx context.Context, username string) (*tg.Channel, error) {
-	log := logger.With("operation", "GetChannel", "username", username)
+// ResolveChat authenticates if necessary and resolves a public username to a
+// channel or supergroup. Basic groups have no username, they are resolved by
+// their numeric chat ID instead.
+func (a *Analyzer) ResolveChat(ctx context.Context, username string) (tg.ChatClass, error) {
+	log := logger.With("operation", "ResolveChat", "username", username)
 
+	a.session.authMu.Lock()
 	err := a.client.Auth().IfNecessary(ctx, auth.NewFlow(a.authenticator, auth.SendCodeOptions{}))
+	a.session.authMu.Unlock()
 	if err != nil {
 		log.Error("Authentication failed", "error", err)
 		return nil, apperrors.NewAnalyzerError("auth", username, fmt.Errorf("%w: %v", apperrors.ErrAuthFailed, err))
 	}
 
 	api := a.client.API()
+	if chatID, err := strconv.ParseInt(username, 10, 64); err == nil {
+		chats, err := api.MessagesGetChats(ctx, []int64{chatID})
+		if err != nil {
+			log.Error("Failed to fetch chat", "error", err)
+			return nil, apperrors.NewAnalyzerError("resolve_chat", username, fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+		}
+		if len(chats.GetChats()) == 0 {
+			log.Warn("Chat not found")
+			return nil, apperrors.NewAnalyzerError("resolve_chat", username, apperrors.ErrChannelNotFound)
+		}
+		return chats.GetChats()[0], nil
+	}
+
 	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
 		Username: username,
 	})
@@ -105,8 +138,22 @@ func (a *Analyzer) GetChannel(ctx context.Context, username string) (*tg.Channel
 		return nil, apperrors.NewAnalyzerError("resolve_username", username, apperrors.ErrChannelNotFound)
 	}
 
-	c, ok := resolved.Chats[0].(*tg.Channel)
-	if !ok {
+	log.Info("Chat resolved successfully", "chat_id", resolved.Chats[0].GetID())
+	return resolved.Chats[0], nil
+}
+
+// GetChannel resolves a broadcast channel, supergroups and basic groups are
+// rejected with ErrNotAChannel.
+func (a *Analyzer) GetChannel(ctx context.Context, username string) (*tg.Channel, error) {
+	log := logger.With("operation", "GetChannel", "username", username)
+
+	chat, err := a.ResolveChat(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := chat.(*tg.Channel)
+	if !ok || c.Megagroup {
 		log.Warn("Resolved chat is not a channel")
 		return nil, apperrors.NewAnalyzerError("resolve_username", username, apperrors.ErrNotAChannel)
 	}
@@ -130,15 +177,60 @@ func (ar *Analyzer) DownloadProfile(ctx context.Context, c *tg.Channel) (string,
 		return "", apperrors.NewAnalyzerError("download_profile", c.Title, apperrors.ErrInvalidPhoto)
 	}
 
+	peer := &tg.InputPeerChannel{
+		AccessHash: c.AccessHash,
+		ChannelID:  c.ID,
+	}
+	return ar.downloadPeerPhoto(ctx, peer, chatPhoto.PhotoID, ForwardKindChannel, c.ID, c.Title)
+}
+
+// downloadProfileOrWarn downloads the channel profile, logging failures since
+// a missing profile picture never fails the analytics.
+func (ar *Analyzer) downloadProfileOrWarn(ctx context.Context, c *tg.Channel, log *slog.Logger) string {
+	profileAddress, err := ar.DownloadProfile(ctx, c)
+	if err != nil {
+		log.Warn("Failed to download channel profile, continuing without it", "error", err)
+		return ""
+	}
+	return profileAddress
+}
+
+// downloadPeerPhoto stores the big version of a peer's profile photo in the
+// bucket as <kind>-<id>.<ext> and returns its profile URL. The kind is one of
+// the ForwardKind constants, users, chats and channels have separate ID
+// spaces that may overlap.
+func (ar *Analyzer) downloadPeerPhoto(ctx context.Context, peer tg.InputPeerClass, photoID int64, kind string, id int64, title string) (string, error) {
+	log := logger.With("operation", "downloadPeerPhoto", "peer_kind", kind, "peer_id", id, "title", title)
+
 	location := &tg.InputPeerPhotoFileLocation{
-		Peer: &tg.InputPeerChannel{
-			AccessHash: c.AccessHash,
-			ChannelID:  c.ID,
-		},
-		PhotoID: chatPhoto.PhotoID,
+		Peer:    peer,
+		PhotoID: photoID,
 		Big:     true,
 	}
 
+	buf, err := ar.downloadFile(ctx, location, log)
+	if err != nil {
+		log.Error("Failed to download profile after retries", "error", err)
+		return "", apperrors.NewAnalyzerError("download_profile", title, fmt.Errorf("%w: %v", apperrors.ErrDownloadFailed, err))
+	}
+
+	fileName, contentType, err := ar.uploadFile(fmt.Sprintf("%s-%d", kind, id), buf, log)
+	if err != nil {
+		log.Error("Failed to upload profile to storage", "error", err)
+		return "", apperrors.NewAnalyzerError("upload_profile", title, fmt.Errorf("%w: %v", apperrors.ErrUploadFailed, err))
+	}
+
+	profileURL := fmt.Sprintf("/profiles/%s", fileName)
+	log.Info("Profile downloaded and uploaded successfully",
+		"profile_url", profileURL,
+		"content_type", contentType)
+
+	return profileURL, nil
+}
+
+// downloadFile streams the file at location into memory, retrying transient
+// failures up to maxRetries times.
+func (ar *Analyzer) downloadFile(ctx context.Context, location tg.InputFileLocationClass, log *slog.Logger) (bytes.Buffer, error) {
 	d := downloader.NewDownloader()
 	var buf bytes.Buffer
 
@@ -160,12 +252,12 @@ func (ar *Analyzer) DownloadProfile(ctx context.Context, c *tg.Channel) (string,
 			buf.Reset()
 		}
 	}
+	return buf, err
+}
 
-	if err != nil {
-		log.Error("Failed to download profile after retries", "error", err)
-		return "", apperrors.NewAnalyzerError("download_profile", c.Title, fmt.Errorf("%w: %v", apperrors.ErrDownloadFailed, err))
-	}
-
+// uploadFile stores buf in the bucket under baseName with an extension guessed
+// from its content, returning the object name and content type.
+func (ar *Analyzer) uploadFile(baseName string, buf bytes.Buffer, log *slog.Logger) (string, string, error) {
 	contentType := http.DetectContentType(buf.Bytes())
 	fileExtensions, err := mime.ExtensionsByType(contentType)
 	if err != nil || len(fileExtensions) == 0 {
@@ -175,20 +267,13 @@ func (ar *Analyzer) DownloadProfile(ctx context.Context, c *tg.Channel) (string,
 		fileExtensions = []string{defaultFileExtension}
 	}
 
-	fileName := fmt.Sprintf("%d%s", c.ID, fileExtensions[0])
-	err = ar.minioClient.UploadProfile(fileName, buf, contentType)
-	if err != nil {
-		log.Error("Failed to upload profile to storage", "error", err)
-		return "", apperrors.NewAnalyzerError("upload_profile", c.Title, fmt.Errorf("%w: %v", apperrors.ErrUploadFailed, err))
+	fileName := baseName + fileExtensions[0]
+	if err := ar.minioClient.UploadProfile(fileName, buf, contentType); err != nil {
+		return "", "", err
 	}
-
-	profileURL := fmt.Sprintf("/profiles/%s", fileName)
-	log.Info("Profile downloaded and uploaded successfully",
-		"profile_url", profileURL,
-		"content_type", contentType)
-
-	return profileURL, nil
+	return fileName, contentType, nil
 }
+
 func (ar *Analyzer) fetchMessageDetails(ctx context.Context, api *tg.Client, channel *tg.Channel, messageID int) (*Message, error) {
 	log := logger.With("operation", "fetchMessageDetails", "channel_id", channel.ID, "message_id", messageID)
 
@@ -220,6 +305,9 @@ func (ar *Analyzer) fetchMessageDetails(ctx context.Context, api *tg.Client, cha
 		Date:  getDateTime(tgMsg.Date),
 		Views: tgMsg.Views,
 	}
+	if tgMsg.Views != 0 {
+		result.EngagementRate = ratio(postEngagement(tgMsg), tgMsg.Views)
+	}
 
 	if tgMsg.Replies.Replies != 0 {
 		result.Comments = tgMsg.Replies.Replies
@@ -229,122 +317,238 @@ func (ar *Analyzer) fetchMessageDetails(ctx context.Context, api *tg.Client, cha
 	return result, nil
 }
 
-func (ar *Analyzer) ProcessAnalytics(username string) (*Analytics, error) {
-	log := logger.With("operation", "ProcessAnalytics", "username", username)
-	log.Info("Starting analytics processing")
+func (ar *Analyzer) fetchFullChannel(ctx context.Context, api *tg.Client, channel *tg.Channel) (*tg.ChannelFull, []tg.ChatClass, error) {
+	log := logger.With("operation", "fetchFullChannel", "channel_id", channel.ID)
 
-	startTime := time.Now()
-	var a Analytics
+	res, err := api.ChannelsGetFullChannel(ctx, channel.AsInput())
+	if err != nil {
+		log.Error("Failed to fetch full channel", "error", err)
+		return nil, nil, apperrors.NewAnalyzerError("fetch_full_channel", channel.Title, fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+	}
 
-	if err := ar.client.Run(context.Background(), func(ctx context.Context) error {
-		channel, err := ar.GetChannel(ctx, username)
-		if err != nil {
-			return err
-		}
+	full, ok := res.FullChat.(*tg.ChannelFull)
+	if !ok {
+		log.Warn("Full chat is not a channel")
+		return nil, nil, apperrors.NewAnalyzerError("fetch_full_channel", channel.Title, apperrors.ErrNotAChannel)
+	}
+	return full, res.Chats, nil
+}
 
-		a = NewAnalytics(channel.Title)
+// fetchFirstMessageDate returns the date of the oldest message still in the
+// channel history, which is the channel creation date unless it was cleared.
+func (ar *Analyzer) fetchFirstMessageDate(ctx context.Context, api *tg.Client, channel *tg.Channel) (time.Time, error) {
+	log := logger.With("operation", "fetchFirstMessageDate", "channel_id", channel.ID)
 
-		// Download channel profile (non-fatal if fails)
-		profileAddress, err := ar.DownloadProfile(ctx, channel)
-		if err != nil {
-			log.Warn("Failed to download channel profile, continuing without it", "error", err)
-		} else {
-			a.ChannelProfile = profileAddress
+	res, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+		Peer:      channel.AsInputPeer(),
+		OffsetID:  1,
+		AddOffset: -1,
+		Limit:     1,
+	})
+	if err != nil {
+		log.Error("Failed to fetch first message", "error", err)
+		return time.Time{}, apperrors.NewAnalyzerError("fetch_first_message", channel.Title, fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+	}
+
+	modified, ok := res.AsModified()
+	if !ok || len(modified.GetMessages()) == 0 {
+		return time.Time{}, apperrors.NewAnalyzerError("fetch_first_message", channel.Title, apperrors.ErrNoMessages)
+	}
+	first, ok := modified.GetMessages()[0].AsNotEmpty()
+	if !ok {
+		return time.Time{}, apperrors.NewAnalyzerError("fetch_first_message", channel.Title, apperrors.ErrNoMessages)
+	}
+	return getDateTime(first.GetDate()), nil
+}
+
+// GetChannelInfo collects the channel metadata snapshot. Only the full channel
+// request is required, a missing creation date is logged and left empty.
+func (ar *Analyzer) GetChannelInfo(ctx context.Context, api *tg.Client, channel *tg.Channel) (ChannelInfo, error) {
+	log := logger.With("operation", "GetChannelInfo", "channel_id", channel.ID)
+
+	info := ChannelInfo{
+		ID:          channel.ID,
+		Username:    channel.Username,
+		Verified:    channel.Verified,
+		Scam:        channel.Scam,
+		Fake:        channel.Fake,
+		BoostsLevel: channel.Level,
+		CapturedAt:  time.Now().UTC(),
+	}
+
+	full, chats, err := ar.fetchFullChannel(ctx, api, channel)
+	if err != nil {
+		return info, err
+	}
+	info.Subscribers = full.ParticipantsCount
+	info.Description = full.About
+	info.LinkedChatID = full.LinkedChatID
+	for _, chat := range chats {
+		linked, ok := chat.(*tg.Channel)
+		if ok && linked.ID == full.LinkedChatID {
+			info.LinkedChatTitle = linked.Title
+			info.LinkedChatUsername = linked.Username
 		}
+	}
 
-		api := ar.client.API()
-		startDate := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
-		minDateUnix := int(startDate.Unix())
-		currentDate := int(time.Now().Unix())
-		offsetID := 0
-		offSet := currentDate
-		currentLoop := 1
-		totalMessages := 0
-
-		log.Info("Fetching channel messages",
-			"channel", channel.Title,
-			"start_date", startDate.Format("2006-01-02"))
-
-		for offSet > minDateUnix {
-			peer := &tg.InputPeerChannel{ChannelID: channel.ID, AccessHash: channel.AccessHash}
-			res, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-				Peer:       peer,
-				OffsetDate: offSet,
-				OffsetID:   offsetID,
-				Limit:      defaultMessageLimit,
-			})
-			if err != nil {
-				log.Warn("Failed to fetch message batch, retrying",
-					"loop", currentLoop,
-					"error", err)
-				time.Sleep(retryDelay)
-				continue
-			}
+	createdAt, err := ar.fetchFirstMessageDate(ctx, api, channel)
+	if err != nil {
+		log.Warn("Failed to fetch channel creation date", "error", err)
+	} else {
+		info.CreatedAt = createdAt
+	}
+	return info, nil
+}
 
-			m, ok := res.(*tg.MessagesChannelMessages)
-			if !ok || m == nil {
-				log.Debug("No more messages or invalid response", "loop", currentLoop)
-				offSet = minDateUnix
-				continue
-			}
+// processChannel crawls a broadcast channel and fetches the details of its
+// highlighted posts.
+func (ar *Analyzer) processChannel(ctx context.Context, a *Analytics, channel *tg.Channel) error {
+	log := logger.With("operation", "processChannel", "channel_id", channel.ID)
 
-			messagesInBatch := len(m.Messages)
-			totalMessages += messagesInBatch
-			offSet = a.updateFromChannelMessages(m)
+	a.Mode = ModeChannel
 
-			log.Debug("Processed message batch",
-				"loop", currentLoop,
-				"messages_in_batch", messagesInBatch,
-				"total_messages", totalMessages)
+	// Download channel profile (non-fatal if fails)
+	a.ChannelProfile = ar.downloadProfileOrWarn(ctx, channel, log)
 
-			currentLoop++
-		}
+	api := ar.client.API()
 
-		log.Info("Message fetching complete",
-			"total_loops", currentLoop-1,
-			"total_messages", totalMessages)
+	// Channel metadata snapshot (non-fatal if fails)
+	info, err := ar.GetChannelInfo(ctx, api, channel)
+	if err != nil {
+		log.Warn("Failed to fetch channel info, continuing without it", "error", err)
+	}
+	a.Info = info
+	if info.LinkedChatID != 0 {
+		a.Discussion = NewDiscussionStats()
+	}
 
-		// Fetch most viewed message details
-		if a.Highlights.MostViewedID != 0 {
-			mostViewed, err := ar.fetchMessageDetails(ctx, api, channel, a.Highlights.MostViewedID)
-			if err != nil {
-				log.Warn("Failed to fetch most viewed message details", "error", err)
-			} else {
-				a.Highlights.MostViewed = *mostViewed
-			}
+	log.Info("Fetching channel messages",
+		"channel", channel.Title,
+		"start_date", a.Window.Start.Format(windowDateLayout))
+
+	peer := &tg.InputPeerChannel{ChannelID: channel.ID, AccessHash: channel.AccessHash}
+	if _, err := ar.crawlHistory(ctx, api, peer, channel.Title, a.Window, log, a.updateFromChannelMessages); err != nil {
+		return err
+	}
+
+	// Forward sources are resolved from every crawled batch, not just the last
+	channelID := a.Highlights.GetMostForwardsSource()
+	a.Highlights.GetMostForwardedFromChannel(a.Forwards.resolvedChannels(), channelID)
+	a.Forwards.GetForwardGraph(ForwardNode{
+		Kind:     ForwardKindChannel,
+		ID:       channel.ID,
+		Title:    channel.Title,
+		Username: channel.Username,
+		Profile:  a.ChannelProfile,
+	})
+	ar.ResolveForwardProfiles(ctx, &a.Forwards)
+
+	// Fetch most viewed message details
+	if a.Highlights.MostViewedID != 0 {
+		mostViewed, err := ar.fetchMessageDetails(ctx, api, channel, a.Highlights.MostViewedID)
+		if err != nil {
+			log.Warn("Failed to fetch most viewed message details", "error", err)
+		} else {
+			a.Highlights.MostViewed = *mostViewed
 		}
+	}
 
-		// Fetch most commented message details
-		if a.Highlights.MostCommentedID != 0 {
-			mostCommented, err := ar.fetchMessageDetails(ctx, api, channel, a.Highlights.MostCommentedID)
-			if err != nil {
-				log.Warn("Failed to fetch most commented message details", "error", err)
-			} else {
-				a.Highlights.MostCommented = *mostCommented
-			}
+	// Fetch most commented message details
+	if a.Highlights.MostCommentedID != 0 {
+		mostCommented, err := ar.fetchMessageDetails(ctx, api, channel, a.Highlights.MostCommentedID)
+		if err != nil {
+			log.Warn("Failed to fetch most commented message details", "error", err)
+		} else {
+			a.Highlights.MostCommented = *mostCommented
 		}
+	}
 
-		// Download most forwarded channel profile (non-fatal if fails)
-		if a.Highlights.MostForwardedChannel != nil {
-			profileUrl, err := ar.DownloadProfile(ctx, a.Highlights.MostForwardedChannel)
+	ar.ResolveCustomReactions(ctx, api, &a.Highlights)
+
+	// Crawl comment threads of the top posts when comments are enabled
+	if a.Discussion != nil {
+		ar.CrawlDiscussions(ctx, api, channel, a.Discussion)
+	}
+
+	// Most forwarded channel profile, the graph already downloaded it unless
+	// the channel was past its profile limit (non-fatal if fails)
+	if source := a.Highlights.MostForwardedChannel; source != nil {
+		profile, resolved := a.Forwards.profile(ForwardKindChannel, source.ID)
+		if !resolved {
+			profileUrl, err := ar.DownloadProfile(ctx, source)
 			if err != nil {
 				log.Warn("Failed to download forwarded channel profile",
-					"forwarded_channel", a.Highlights.MostForwardedChannel.Title,
+					"forwarded_channel", source.Title,
 					"error", err)
-			} else {
-				a.Highlights.MostForwardedSource.Profile = profileUrl
 			}
+			profile = profileUrl
 		}
+		a.Highlights.MostForwardedSource.Profile = profile
+	}
 
-		return nil
-	}); err != nil {
+	return nil
+}
+
+// ProcessAnalytics resolves username and analyzes it in channel mode for
+// broadcast channels or in group mode for supergroups and basic groups. It
+// fails when ctx is done before the analysis completes.
+func (ar *Analyzer) ProcessAnalytics(ctx context.Context, username string, window Window) (*Analytics, error) {
+	log := logger.With("operation", "ProcessAnalytics", "username", username, "window", window.Key())
+	log.Info("Starting analytics processing")
+
+	startTime := time.Now()
+	var a Analytics
+
+	err := ar.run(ctx, func(ctx context.Context) error {
+		chat, err := ar.ResolveChat(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		switch c := chat.(type) {
+		case *tg.Channel:
+			a = NewAnalytics(c.Title)
+			a.SetWindow(window)
+			a.Sentiment.scorer = ar.scorer
+			if c.Megagroup {
+				return ar.processGroup(ctx, &a, c)
+			}
+			return ar.processChannel(ctx, &a, c)
+		case *tg.Chat:
+			a = NewAnalytics(c.Title)
+			a.SetWindow(window)
+			a.Sentiment.scorer = ar.scorer
+			return ar.processGroup(ctx, &a, c)
+		default:
+			log.Warn("Resolved chat is neither a channel nor a group")
+			return apperrors.NewAnalyzerError("resolve_username", username, apperrors.ErrNotAChannel)
+		}
+	})
+	if err == nil {
+		// The crawls stop early when ctx is done, a partial result is not
+		// returned as if it were complete
+		err = ctx.Err()
+	}
+	if err != nil {
 		log.Error("Analytics processing failed", "error", err, "duration", time.Since(startTime))
 		return nil, err
 	}
 
 	a.GetLongestStreak()
+	a.Trends.GetCadence()
+	a.GetEmojiStats()
+	a.GetTopHashtags()
+	a.GetEngagementStats(a.Info.Subscribers)
+	a.Edits.GetEditStats(a.Totals.TotalPosts)
+	a.Authors.GetLeaderboard()
+	a.Polls.GetPollStats()
+	a.Sentiment.GetSentimentStats()
+	a.Languages.GetLanguageStats()
+	a.Topics.GetTopics()
+	a.Text.GetTextStats()
 
 	log.Info("Analytics processing complete",
+		"mode", a.Mode,
 		"duration", time.Since(startTime),
 		"total_posts", a.Totals.TotalPosts,
 		"total_views", a.Totals.TotalViews)
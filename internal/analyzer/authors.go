@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+const activeHourLimit = 3
+
+type AuthorStats struct {
+	Author           string      `json:"author"`
+	Posts            int         `json:"posts"`
+	TotalViews       int         `json:"total_views"`
+	AverageViews     float64     `json:"average_views"`
+	TotalReactions   int         `json:"total_reactions"`
+	AverageReactions float64     `json:"average_reactions"`
+	BestPostID       int         `json:"best_post_id"`
+	BestPost         Message     `json:"best_post"`
+	PostsByHour      map[int]int `json:"posts_by_hour"`
+	ActiveHours      []int       `json:"active_hours"`
+}
+
+// AuthorBreakdown splits the posts of channels with signatures enabled by
+// their signed author. Unsigned posts are left out.
+type AuthorBreakdown struct {
+	SignedPosts int           `json:"signed_posts"`
+	Leaderboard []AuthorStats `json:"leaderboard"`
+
+	location *time.Location
+	byAuthor map[string]*AuthorStats
+}
+
+func (b *AuthorBreakdown) UpdateAuthors(msg *tg.Message) {
+	if msg.PostAuthor == "" {
+		return
+	}
+	author, ok := b.byAuthor[msg.PostAuthor]
+	if !ok {
+		author = &AuthorStats{Author: msg.PostAuthor, PostsByHour: make(map[int]int)}
+		b.byAuthor[msg.PostAuthor] = author
+	}
+
+	dateTime := getDateTime(msg.Date)
+	if b.location != nil {
+		dateTime = dateTime.In(b.location)
+	}
+	b.SignedPosts += 1
+	author.Posts += 1
+	author.TotalViews += msg.Views
+	author.TotalReactions += countNumOfReactions(msg.Reactions).total
+	author.PostsByHour[dateTime.Hour()] += 1
+	if author.BestPostID == 0 || msg.Views > author.BestPost.Views {
+		author.BestPostID = msg.ID
+		author.BestPost = Message{
+			Text:           msg.Message,
+			Views:          msg.Views,
+			Comments:       msg.Replies.Replies,
+			Date:           dateTime,
+			EngagementRate: ratio(postEngagement(msg), msg.Views),
+		}
+	}
+}
+
+// GetLeaderboard ranks the authors by total views once the crawl is complete.
+func (b *AuthorBreakdown) GetLeaderboard() {
+	leaderboard := make([]AuthorStats, 0, len(b.byAuthor))
+	for _, author := range b.byAuthor {
+		author.AverageViews = ratio(author.TotalViews, author.Posts)
+		author.AverageReactions = ratio(author.TotalReactions, author.Posts)
+		author.ActiveHours = activeHours(author.PostsByHour)
+		leaderboard = append(leaderboard, *author)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].TotalViews == leaderboard[j].TotalViews {
+			return leaderboard[i].Author < leaderboard[j].Author
+		}
+		return leaderboard[i].TotalViews > leaderboard[j].TotalViews
+	})
+	b.Leaderboard = leaderboard
+}
+
+// activeHours returns the hours with the most posts, busiest first.
+func activeHours(postsByHour map[int]int) []int {
+	hours := make([]int, 0, len(postsByHour))
+	for hour := range postsByHour {
+		hours = append(hours, hour)
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		if postsByHour[hours[i]] == postsByHour[hours[j]] {
+			return hours[i] < hours[j]
+		}
+		return postsByHour[hours[i]] > postsByHour[hours[j]]
+	})
+	if len(hours) > activeHourLimit {
+		hours = hours[:activeHourLimit]
+	}
+	return hours
+}
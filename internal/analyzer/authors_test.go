@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestAuthorLeaderboard(t *testing.T) {
+	a := testAnalytics(t, "Africa/Addis_Ababa")
+
+	at := func(hour int) int {
+		return int(time.Date(2025, time.May, 4, hour, 0, 0, 0, time.UTC).Unix())
+	}
+	posts := []*tg.Message{
+		{ID: 1, PostAuthor: "Abebe", Views: 100, Date: at(6)},
+		{ID: 2, PostAuthor: "Abebe", Views: 300, Date: at(6), Message: "best of Abebe"},
+		{ID: 3, PostAuthor: "Abebe", Views: 300, Date: at(9)},
+		{ID: 4, PostAuthor: "Sara", Views: 500, Date: at(12), Message: "best of Sara", Reactions: reactionsOf(emojiReaction("👍", 4))},
+		{ID: 5, PostAuthor: "Sara", Views: 200, Date: at(12)},
+		// Ties on total views are ranked by name
+		{ID: 6, PostAuthor: "Kebede", Views: 700, Date: at(18)},
+		// Unsigned posts are left out
+		{ID: 7, Views: 10000, Date: at(18)},
+	}
+	for _, msg := range posts {
+		a.Authors.UpdateAuthors(msg)
+	}
+	a.Authors.GetLeaderboard()
+
+	if a.Authors.SignedPosts != 6 {
+		t.Fatalf("signed posts = %d, want 6", a.Authors.SignedPosts)
+	}
+	order := make([]string, 0, len(a.Authors.Leaderboard))
+	for _, author := range a.Authors.Leaderboard {
+		order = append(order, author.Author)
+	}
+	if want := []string{"Abebe", "Kebede", "Sara"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("leaderboard = %v, want %v", order, want)
+	}
+
+	abebe, sara := a.Authors.Leaderboard[0], a.Authors.Leaderboard[2]
+	// The first post with the most views stays the best one
+	if abebe.BestPostID != 2 || abebe.BestPost.Text != "best of Abebe" || abebe.BestPost.Views != 300 {
+		t.Fatalf("Abebe best post = %d %+v, want post 2", abebe.BestPostID, abebe.BestPost)
+	}
+	if abebe.Posts != 3 || abebe.TotalViews != 700 || !closeTo(abebe.AverageViews, 700.0/3) {
+		t.Fatalf("Abebe = %d posts, %d views (%v average), want 3, 700", abebe.Posts, abebe.TotalViews, abebe.AverageViews)
+	}
+	// Hours are bucketed in the window timezone, three hours ahead of UTC
+	if want := []int{9, 12}; !reflect.DeepEqual(abebe.ActiveHours, want) {
+		t.Fatalf("Abebe active hours = %v, want %v", abebe.ActiveHours, want)
+	}
+	if sara.BestPostID != 4 || sara.BestPost.Text != "best of Sara" || !closeTo(sara.BestPost.EngagementRate, 0.008) {
+		t.Fatalf("Sara best post = %d %+v, want post 4 with a 0.008 engagement rate", sara.BestPostID, sara.BestPost)
+	}
+	if sara.TotalReactions != 4 || sara.AverageReactions != 2 {
+		t.Fatalf("Sara reactions = %d (%v average), want 4 (2)", sara.TotalReactions, sara.AverageReactions)
+	}
+}
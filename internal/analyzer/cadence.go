@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// GetCadence derives the posting rhythm once the crawl is complete: the
+// average views heatmap, the best performing hour and weekday by average
+// views, the average gap between posts, how bursty the posting is and the
+// share of weeks with at least one post.
+func (t *TimeTrends) GetCadence() {
+	var viewsByHour, postsByHour [24]int
+	var viewsByDay, postsByDay [7]int
+	for day := 0; day < 7; day++ {
+		for hour := 0; hour < 24; hour++ {
+			posts := t.PostsHeatmap[day][hour]
+			views := t.viewsHeatmap[day][hour]
+			t.AverageViewsHeatmap[day][hour] = ratio(views, posts)
+			viewsByHour[hour] += views
+			postsByHour[hour] += posts
+			viewsByDay[day] += views
+			postsByDay[day] += posts
+		}
+	}
+
+	t.BestPerformingHour = -1
+	best := -1.0
+	for hour := 0; hour < 24; hour++ {
+		if avg := ratio(viewsByHour[hour], postsByHour[hour]); postsByHour[hour] != 0 && avg > best {
+			t.BestPerformingHour, best = hour, avg
+		}
+	}
+	t.BestPerformingDay = ""
+	best = -1.0
+	for day := 0; day < 7; day++ {
+		if avg := ratio(viewsByDay[day], postsByDay[day]); postsByDay[day] != 0 && avg > best {
+			t.BestPerformingDay, best = time.Weekday(day).String(), avg
+		}
+	}
+
+	dates := make([]int, len(t.postDates))
+	copy(dates, t.postDates)
+	sort.Ints(dates)
+
+	gaps := make([]float64, 0, len(dates))
+	for i := 1; i < len(dates); i++ {
+		gaps = append(gaps, float64(dates[i]-dates[i-1])/3600)
+	}
+	t.AverageGapHours = mean(gaps)
+	t.Burstiness = burstiness(gaps)
+	t.WeeklyConsistency = t.weeklyConsistency(dates)
+}
+
+// burstiness is (σ-μ)/(σ+μ) of the gaps between posts: -1 for a perfectly
+// regular schedule, around 0 for random posting and close to 1 when posts come
+// in bursts separated by long silences.
+func burstiness(gaps []float64) float64 {
+	if len(gaps) == 0 {
+		return 0
+	}
+	mu := mean(gaps)
+	variance := 0.0
+	for _, gap := range gaps {
+		variance += (gap - mu) * (gap - mu)
+	}
+	sigma := math.Sqrt(variance / float64(len(gaps)))
+	if sigma+mu == 0 {
+		return 0
+	}
+	return (sigma - mu) / (sigma + mu)
+}
+
+// weeklyConsistency is the share of weeks, Monday to Sunday in the trends
+// timezone (UTC when none is set), between the first and the last post that
+// contain a post.
+func (t *TimeTrends) weeklyConsistency(sortedDates []int) float64 {
+	if len(sortedDates) == 0 {
+		return 0
+	}
+	loc := t.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	weekOf := func(date int) time.Time {
+		local := getDateTime(date).In(loc)
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+
+	active := make(map[time.Time]bool)
+	for _, date := range sortedDates {
+		active[weekOf(date)] = true
+	}
+	first := weekOf(sortedDates[0])
+	last := weekOf(sortedDates[len(sortedDates)-1])
+	weeks := int(last.Sub(first).Hours()/24/7) + 1
+	return ratio(len(active), weeks)
+}
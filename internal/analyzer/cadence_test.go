@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestCadence(t *testing.T) {
+	a := testAnalytics(t, "Africa/Addis_Ababa")
+
+	// Two posts at 22:00 UTC on Monday 2025-01-06, which is 01:00 on Tuesday in
+	// Addis Ababa, and one in the third week
+	base := time.Date(2025, time.January, 6, 22, 0, 0, 0, time.UTC)
+	posts := []struct {
+		at    time.Time
+		views int
+	}{
+		{base, 100},
+		{base.Add(time.Hour), 300},
+		{base.AddDate(0, 0, 14), 50},
+	}
+	for _, p := range posts {
+		a.Trends.UpdateTrends(&tg.Message{Date: int(p.at.Unix()), Views: p.views})
+	}
+	a.Trends.GetCadence()
+
+	if got := a.Trends.PostsHeatmap[time.Tuesday][1]; got != 2 {
+		t.Fatalf("posts at Tuesday 01:00 = %d, want 2", got)
+	}
+	if a.Trends.BestPerformingHour != 2 || a.Trends.BestPerformingDay != "Tuesday" {
+		t.Fatalf("best performing = %s %d, want Tuesday 2", a.Trends.BestPerformingDay, a.Trends.BestPerformingHour)
+	}
+	if got := a.Trends.WeeklyConsistency; got < 0.66 || got > 0.67 {
+		t.Fatalf("weekly consistency = %v, want 2/3", got)
+	}
+	if a.Trends.AverageGapHours != 168 {
+		t.Fatalf("average gap = %v hours, want 168", a.Trends.AverageGapHours)
+	}
+	if got := burstiness([]float64{24, 24, 24}); got != -1 {
+		t.Fatalf("burstiness of a regular schedule = %v, want -1", got)
+	}
+	if key := a.Window.Key(); key != "2025-01-01_2026-01-01@Africa/Addis_Ababa" {
+		t.Fatalf("window key = %s", key)
+	}
+}
+
+func TestWeeklyConsistencyDefaultsToUTC(t *testing.T) {
+	// Weeks must not depend on the timezone of the server
+	local := time.Local
+	defer func() { time.Local = local }()
+	time.Local = time.FixedZone("UTC+3", 3*60*60)
+
+	// 01:00 UTC on Monday 2025-01-06 and 23:00 UTC on Sunday 2025-01-19 fall in
+	// two consecutive weeks in UTC, three hours ahead the second one is a
+	// Monday and leaves an empty week between them
+	monday := time.Date(2025, time.January, 6, 1, 0, 0, 0, time.UTC)
+	sunday := time.Date(2025, time.January, 19, 23, 0, 0, 0, time.UTC)
+	dates := []int{int(monday.Unix()), int(sunday.Unix())}
+
+	var trends TimeTrends
+	if got := trends.weeklyConsistency(dates); got != 1 {
+		t.Fatalf("weekly consistency = %v, want 1 for two active weeks", got)
+	}
+}
@@ -0,0 +1,217 @@
+package analyzer
+
+import "sort"
+
+type MetricDelta struct {
+	Base          float64  `json:"base"`
+	Current       float64  `json:"current"`
+	Delta         float64  `json:"delta"`
+	PercentChange *float64 `json:"percent_change"`
+}
+
+func newMetricDelta(base, current float64) MetricDelta {
+	d := MetricDelta{Base: base, Current: current, Delta: current - base}
+	if base != 0 {
+		change := (current - base) / base * 100
+		d.PercentChange = &change
+	}
+	return d
+}
+
+type HourShift struct {
+	BaseHour    int `json:"base_hour"`
+	CurrentHour int `json:"current_hour"`
+	Shift       int `json:"shift"`
+}
+
+type ShareChange struct {
+	BaseShare    float64 `json:"base_share"`
+	CurrentShare float64 `json:"current_share"`
+	Delta        float64 `json:"delta"`
+}
+
+type Comparison struct {
+	BaseWindow       Window                 `json:"base_window"`
+	CurrentWindow    Window                 `json:"current_window"`
+	Totals           map[string]MetricDelta `json:"totals"`
+	BestHour         HourShift              `json:"best_hour"`
+	NewTopHashtags   []string               `json:"new_top_hashtags"`
+	ContentMixChange map[string]ShareChange `json:"content_mix_change"`
+}
+
+// totalsOf flattens every total tracked by the analytics so two windows can be
+// compared metric by metric.
+func totalsOf(a *Analytics) map[string]float64 {
+	return map[string]float64{
+		"views":            float64(a.Totals.TotalViews),
+		"comments":         float64(a.Totals.TotalComments),
+		"reactions":        float64(a.Totals.TotalReactions),
+		"posts":            float64(a.Totals.TotalPosts),
+		"forwards":         float64(a.Totals.TotalForwards),
+		"shares":           float64(a.Engagement.TotalShares),
+		"engagements":      float64(a.Engagement.TotalEngagements),
+		"engagement_rate":  a.Engagement.EngagementRate,
+		"emojis":           float64(a.Emojis.TotalEmojis),
+		"paid_stars":       float64(a.Highlights.PaidReactions.TotalStars),
+		"subscribers":      float64(a.Info.Subscribers),
+		"longest_streak":   float64(a.Trends.LongestPostingStreak),
+		"most_viewed":      float64(a.Highlights.MostViewedCount),
+		"reactions_per_1k": a.Engagement.ReactionsPer1kViews,
+		"hashtags_used":    float64(len(a.Content.Hashtags)),
+		"posts_with_emoji": float64(a.Emojis.PostsWithEmoji),
+		"most_commented":   float64(a.Highlights.MostCommentedCount),
+	}
+}
+
+// Compare diffs the analytics of two windows of the same channel, base being
+// the older one.
+func Compare(base, current *Analytics) Comparison {
+	c := Comparison{
+		BaseWindow:       base.Window,
+		CurrentWindow:    current.Window,
+		Totals:           make(map[string]MetricDelta),
+		ContentMixChange: make(map[string]ShareChange),
+	}
+
+	baseTotals := totalsOf(base)
+	for name, value := range totalsOf(current) {
+		c.Totals[name] = newMetricDelta(baseTotals[name], value)
+	}
+
+	c.BestHour = HourShift{
+		BaseHour:    base.Trends.BestHour(),
+		CurrentHour: current.Trends.BestHour(),
+	}
+	if c.BestHour.BaseHour >= 0 && c.BestHour.CurrentHour >= 0 {
+		c.BestHour.Shift = hourDistance(c.BestHour.BaseHour, c.BestHour.CurrentHour)
+	}
+
+	baseTop := make(map[string]bool)
+	for _, tag := range rankKeys(base.Content.Hashtags, topHashtagLimit) {
+		baseTop[tag] = true
+	}
+	c.NewTopHashtags = make([]string, 0)
+	for _, tag := range rankKeys(current.Content.Hashtags, topHashtagLimit) {
+		if !baseTop[tag] {
+			c.NewTopHashtags = append(c.NewTopHashtags, tag)
+		}
+	}
+
+	types := make(map[string]bool)
+	for kind := range base.Content.PostsByType {
+		types[kind] = true
+	}
+	for kind := range current.Content.PostsByType {
+		types[kind] = true
+	}
+	for kind := range types {
+		baseShare := ratio(base.Content.PostsByType[kind], base.Totals.TotalPosts)
+		currentShare := ratio(current.Content.PostsByType[kind], current.Totals.TotalPosts)
+		c.ContentMixChange[kind] = ShareChange{
+			BaseShare:    baseShare,
+			CurrentShare: currentShare,
+			Delta:        currentShare - baseShare,
+		}
+	}
+	return c
+}
+
+// hourDistance returns the signed shortest shift from one hour of the day to
+// another, so 23 -> 1 is +2 rather than -22.
+func hourDistance(from, to int) int {
+	shift := (to - from + 24) % 24
+	if shift > 12 {
+		shift -= 24
+	}
+	return shift
+}
+
+type ChannelMetric struct {
+	Value float64 `json:"value"`
+	Rank  int     `json:"rank"`
+}
+
+type ChannelRow struct {
+	Username    string                   `json:"username"`
+	ChannelName string                   `json:"channel_name"`
+	Metrics     map[string]ChannelMetric `json:"metrics"`
+}
+
+type ChannelComparison struct {
+	Window   Window       `json:"window"`
+	Metrics  []string     `json:"metrics"`
+	Channels []ChannelRow `json:"channels"`
+}
+
+// normalizedMetrics are size independent so channels of different sizes can
+// be ranked against each other, raw reach is kept for context.
+var normalizedMetrics = []string{
+	"subscribers",
+	"posts",
+	"views_per_post",
+	"comments_per_post",
+	"shares_per_post",
+	"engagement_rate",
+	"median_post_engagement_rate",
+	"reactions_per_1k_views",
+	"engagement_per_subscriber",
+	"views_per_subscriber",
+	"longest_streak",
+}
+
+func normalizedMetricsOf(a *Analytics) map[string]float64 {
+	posts := a.Totals.TotalPosts
+	return map[string]float64{
+		"subscribers":                 float64(a.Info.Subscribers),
+		"posts":                       float64(posts),
+		"views_per_post":              ratio(a.Totals.TotalViews, posts),
+		"comments_per_post":           ratio(a.Totals.TotalComments, posts),
+		"shares_per_post":             ratio(a.Engagement.TotalShares, posts),
+		"engagement_rate":             a.Engagement.EngagementRate,
+		"median_post_engagement_rate": a.Engagement.PostEngagementRate.Median,
+		"reactions_per_1k_views":      a.Engagement.ReactionsPer1kViews,
+		"engagement_per_subscriber":   a.Engagement.EngagementPerSubscriber,
+		"views_per_subscriber":        ratio(a.Totals.TotalViews, a.Info.Subscribers),
+		"longest_streak":              float64(a.Trends.LongestPostingStreak),
+	}
+}
+
+// CompareChannels builds a side by side table of the given channels' analytics
+// with every metric ranked, 1 being the best. Channels tied on a metric share
+// the same rank.
+func CompareChannels(window Window, analytics map[string]*Analytics) ChannelComparison {
+	usernames := make([]string, 0, len(analytics))
+	for username := range analytics {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	c := ChannelComparison{
+		Window:   window,
+		Metrics:  normalizedMetrics,
+		Channels: make([]ChannelRow, 0, len(usernames)),
+	}
+	values := make([]map[string]float64, 0, len(usernames))
+	for _, username := range usernames {
+		a := analytics[username]
+		values = append(values, normalizedMetricsOf(a))
+		c.Channels = append(c.Channels, ChannelRow{
+			Username:    username,
+			ChannelName: a.ChannelName,
+			Metrics:     make(map[string]ChannelMetric),
+		})
+	}
+
+	for _, metric := range normalizedMetrics {
+		for i := range c.Channels {
+			rank := 1
+			for j := range c.Channels {
+				if values[j][metric] > values[i][metric] {
+					rank += 1
+				}
+			}
+			c.Channels[i].Metrics[metric] = ChannelMetric{Value: values[i][metric], Rank: rank}
+		}
+	}
+	return c
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestAnalytics(year, posts, views, bestHour int, hashtags map[string]int) *Analytics {
+	a := NewAnalytics("test")
+	a.Window = YearWindow(year)
+	a.Totals.TotalPosts = posts
+	a.Totals.TotalViews = views
+	a.Trends.PostsByHour[bestHour] = posts
+	a.Content.PostsByType["text"] = posts
+	for tag, cnt := range hashtags {
+		a.Content.Hashtags[tag] = cnt
+	}
+	return &a
+}
+
+func TestCompare(t *testing.T) {
+	base := newTestAnalytics(2024, 10, 1000, 22, map[string]int{"#news": 5})
+	current := newTestAnalytics(2025, 15, 1340, 1, map[string]int{"#news": 3, "#sport": 4})
+	current.Content.PostsByType["photo"] = 5
+
+	c := Compare(base, current)
+
+	views := c.Totals["views"]
+	if views.Delta != 340 || views.PercentChange == nil || *views.PercentChange != 34 {
+		t.Fatalf("views delta = %+v, want delta 340 and 34%% change", views)
+	}
+	if subscribers := c.Totals["subscribers"]; subscribers.PercentChange != nil {
+		t.Fatalf("percent change from zero base should be nil, got %v", *subscribers.PercentChange)
+	}
+	if c.BestHour.Shift != 3 {
+		t.Fatalf("best hour shift = %d, want 3", c.BestHour.Shift)
+	}
+	if !reflect.DeepEqual(c.NewTopHashtags, []string{"#sport"}) {
+		t.Fatalf("new top hashtags = %v, want [#sport]", c.NewTopHashtags)
+	}
+	if photo := c.ContentMixChange["photo"]; photo.BaseShare != 0 || photo.Delta <= 0 {
+		t.Fatalf("photo share change = %+v, want growth from zero", photo)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow(0, "2024-03-01", "2024-03-31")
+	if err != nil {
+		t.Fatalf("ParseWindow returned error: %v", err)
+	}
+	if w.Key() != "2024-03-01_2024-04-01" {
+		t.Fatalf("window key = %s, want 2024-03-01_2024-04-01", w.Key())
+	}
+
+	if _, err := ParseWindow(2024, "2024-01-01", ""); err == nil {
+		t.Fatalf("expected error when combining year with from")
+	}
+	if _, err := ParseWindow(0, "2024-05-01", "2024-04-01"); err == nil {
+		t.Fatalf("expected error for reversed window")
+	}
+	if !DefaultWindow().IsDefault() {
+		t.Fatalf("default window should report IsDefault")
+	}
+}
+
+func TestCompareChannels(t *testing.T) {
+	big := newTestAnalytics(2024, 10, 5000, 9, nil)
+	big.ChannelName = "Big"
+	small := newTestAnalytics(2024, 10, 1000, 9, nil)
+	small.ChannelName = "Small"
+	tied := newTestAnalytics(2024, 10, 5000, 9, nil)
+	tied.ChannelName = "Tied"
+
+	c := CompareChannels(YearWindow(2024), map[string]*Analytics{"small": small, "tied": tied, "big": big})
+
+	var order []string
+	for _, row := range c.Channels {
+		order = append(order, row.Username)
+	}
+	if !reflect.DeepEqual(order, []string{"big", "small", "tied"}) {
+		t.Fatalf("channels = %v, want them sorted by username", order)
+	}
+
+	ranks := map[string]int{}
+	for _, row := range c.Channels {
+		metric := row.Metrics["views_per_post"]
+		ranks[row.Username] = metric.Rank
+		if row.Username == "small" && metric.Value != 100 {
+			t.Fatalf("small views per post = %v, want 100", metric.Value)
+		}
+	}
+	if !reflect.DeepEqual(ranks, map[string]int{"big": 1, "tied": 1, "small": 3}) {
+		t.Fatalf("views per post ranks = %v, want ties sharing rank 1 and the next rank skipped", ranks)
+	}
+	for _, row := range c.Channels {
+		if rank := row.Metrics["posts"].Rank; rank != 1 {
+			t.Fatalf("%s posts rank = %d, want every channel tied at 1", row.Username, rank)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+)
+
+// batchHandler consumes one page of history and returns the offset date to
+// continue from. Returning a date at or before the window start stops the crawl.
+type batchHandler func(m tg.ModifiedMessagesMessages) int
+
+// crawlHistory pages backwards through the history of peer, starting at the end
+// of window, and hands every page to handle. It returns the number of messages
+// fetched, and an error when ctx is done or a page still fails after
+// maxRetries attempts, e.g. for a private chat. The pages handled until then
+// are kept.
+func (ar *Analyzer) crawlHistory(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, name string, window Window, log *slog.Logger, handle batchHandler) (int, error) {
+	minDateUnix := window.startUnix()
+	offsetID := 0
+	offSet := window.endUnix()
+	currentLoop := 1
+	totalMessages := 0
+	failures := 0
+
+	for offSet > minDateUnix {
+		if ctx.Err() != nil {
+			log.Warn("Crawl cancelled", "loop", currentLoop, "error", ctx.Err())
+			return totalMessages, ctx.Err()
+		}
+
+		res, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:       peer,
+			OffsetDate: offSet,
+			OffsetID:   offsetID,
+			Limit:      defaultMessageLimit,
+		})
+		if err != nil {
+			failures++
+			if failures >= maxRetries {
+				log.Error("Failed to fetch message batch, giving up",
+					"loop", currentLoop,
+					"attempts", failures,
+					"error", err)
+				return totalMessages, apperrors.NewAnalyzerError("fetch_history", name, fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+			}
+
+			// Flood waits tell how long to wait, other errors back off
+			wait := retryDelay * time.Duration(failures)
+			if d, ok := tgerr.AsFloodWait(err); ok {
+				wait = d
+			}
+			log.Warn("Failed to fetch message batch, retrying",
+				"loop", currentLoop,
+				"attempt", failures,
+				"wait", wait,
+				"error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+			continue
+		}
+		failures = 0
+
+		m, ok := res.AsModified()
+		if !ok || m == nil {
+			log.Debug("No more messages or invalid response", "loop", currentLoop)
+			offSet = minDateUnix
+			continue
+		}
+
+		messagesInBatch := len(m.GetMessages())
+		totalMessages += messagesInBatch
+		offSet = handle(m)
+
+		log.Debug("Processed message batch",
+			"loop", currentLoop,
+			"messages_in_batch", messagesInBatch,
+			"total_messages", totalMessages)
+
+		currentLoop++
+	}
+
+	log.Info("Message fetching complete",
+		"total_loops", currentLoop-1,
+		"total_messages", totalMessages)
+
+	return totalMessages, nil
+}
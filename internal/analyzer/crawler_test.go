@@ -0,0 +1,56 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+)
+
+// failingInvoker answers every call with err.
+type failingInvoker struct {
+	calls int
+	err   error
+}
+
+func (f *failingInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	f.calls++
+	return f.err
+}
+
+func TestCrawlHistoryGivesUp(t *testing.T) {
+	// A zero flood wait keeps the retries instant
+	invoker := &failingInvoker{err: tgerr.New(420, "FLOOD_WAIT_0")}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var ar Analyzer
+	handled := 0
+	n, err := ar.crawlHistory(context.Background(), tg.NewClient(invoker), &tg.InputPeerEmpty{}, "private", YearWindow(2024), log,
+		func(m tg.ModifiedMessagesMessages) int { handled++; return 0 })
+
+	if !errors.Is(err, apperrors.ErrTelegramAPI) {
+		t.Fatalf("expected a Telegram API error, got %v", err)
+	}
+	if invoker.calls != maxRetries || n != 0 || handled != 0 {
+		t.Fatalf("expected %d attempts and nothing handled, got %d attempts, %d messages, %d pages", maxRetries, invoker.calls, n, handled)
+	}
+}
+
+func TestCrawlHistoryCancelled(t *testing.T) {
+	invoker := &failingInvoker{err: errors.New("unreachable")}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ar Analyzer
+	_, err := ar.crawlHistory(ctx, tg.NewClient(invoker), &tg.InputPeerEmpty{}, "channel", YearWindow(2024), slog.New(slog.NewTextHandler(io.Discard, nil)),
+		func(m tg.ModifiedMessagesMessages) int { return 0 })
+	if !errors.Is(err, context.Canceled) || invoker.calls != 0 {
+		t.Fatalf("expected the cancellation before any call, got %v after %d calls", err, invoker.calls)
+	}
+}
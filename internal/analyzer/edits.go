@@ -0,0 +1,74 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+// EditStats tracks how often posts are corrected after publishing and
+// estimates how many were deleted. Telegram only keeps the date of the last
+// edit, so the time to edit is measured up to that one.
+type EditStats struct {
+	EditedPosts           int            `json:"edited_posts"`
+	EditedRatio           float64        `json:"edited_ratio"`
+	MedianMinutesToEdit   float64        `json:"median_minutes_to_edit"`
+	EstimatedDeletedPosts int            `json:"estimated_deleted_posts"`
+	DeletedPostsByMonth   map[string]int `json:"deleted_posts_by_month"`
+
+	location      *time.Location
+	minutesToEdit []float64
+	datesByID     map[int]int
+	skipDeletions bool
+}
+
+func (e *EditStats) UpdateEdits(msg *tg.Message) {
+	// EditHide marks edits Telegram does not show, e.g. bot keyboard updates
+	if msg.EditDate == 0 || msg.EditHide || msg.EditDate < msg.Date {
+		return
+	}
+	e.EditedPosts += 1
+	e.minutesToEdit = append(e.minutesToEdit, float64(msg.EditDate-msg.Date)/60)
+}
+
+// UpdateMessageID records the ID of every message in the window, service
+// messages included since they take IDs as well.
+func (e *EditStats) UpdateMessageID(id int, date int) {
+	if e.skipDeletions {
+		return
+	}
+	e.datesByID[id] = date
+}
+
+// GetEditStats derives the ratios once the crawl is complete. Channel and
+// supergroup message IDs are sequential, so every missing ID between two
+// crawled messages is counted as a deleted post in the month of the message
+// that follows the gap.
+func (e *EditStats) GetEditStats(totalPosts int) {
+	e.EditedRatio = ratio(e.EditedPosts, totalPosts)
+	e.MedianMinutesToEdit = median(e.minutesToEdit)
+	if e.skipDeletions {
+		return
+	}
+
+	ids := make([]int, 0, len(e.datesByID))
+	for id := range e.datesByID {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	e.EstimatedDeletedPosts = 0
+	for i := 1; i < len(ids); i++ {
+		missing := ids[i] - ids[i-1] - 1
+		if missing <= 0 {
+			continue
+		}
+		dateTime := getDateTime(e.datesByID[ids[i]])
+		if e.location != nil {
+			dateTime = dateTime.In(e.location)
+		}
+		e.DeletedPostsByMonth[MonthKey(dateTime)] += missing
+		e.EstimatedDeletedPosts += missing
+	}
+}
@@ -0,0 +1,36 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestEditStats(t *testing.T) {
+	a := testAnalytics(t, "")
+
+	jan := int(time.Date(2025, time.January, 10, 12, 0, 0, 0, time.UTC).Unix())
+	feb := int(time.Date(2025, time.February, 3, 12, 0, 0, 0, time.UTC).Unix())
+	messages := []*tg.Message{
+		{ID: 10, Date: jan, EditDate: jan + 120},
+		{ID: 11, Date: jan, EditDate: jan + 600},
+		{ID: 15, Date: feb},
+		{ID: 16, Date: feb, EditDate: feb + 60, EditHide: true},
+	}
+	for _, msg := range messages {
+		a.Edits.UpdateMessageID(msg.ID, msg.Date)
+		a.Edits.UpdateEdits(msg)
+	}
+	a.Edits.GetEditStats(len(messages))
+
+	if a.Edits.EditedPosts != 2 || a.Edits.EditedRatio != 0.5 {
+		t.Fatalf("edited posts = %d (ratio %v), want 2 (0.5)", a.Edits.EditedPosts, a.Edits.EditedRatio)
+	}
+	if a.Edits.MedianMinutesToEdit != 6 {
+		t.Fatalf("median minutes to edit = %v, want 6", a.Edits.MedianMinutesToEdit)
+	}
+	if a.Edits.EstimatedDeletedPosts != 3 || a.Edits.DeletedPostsByMonth["2025-February"] != 3 {
+		t.Fatalf("deleted posts = %d by month %v, want 3 in 2025-February", a.Edits.EstimatedDeletedPosts, a.Edits.DeletedPostsByMonth)
+	}
+}
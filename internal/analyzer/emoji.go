@@ -0,0 +1,168 @@
+package analyzer
+
+const (
+	zeroWidthJoiner   = '\u200D'
+	variationEmoji    = '\uFE0F'
+	variationText     = '\uFE0E'
+	combiningKeycap   = '\u20E3'
+	skinToneFirst     = '\U0001F3FB'
+	skinToneLast      = '\U0001F3FF'
+	regionalFirst     = '\U0001F1E6'
+	regionalLast      = '\U0001F1FF'
+	tagFirst          = '\U000E0020'
+	tagCancel         = '\U000E007F'
+	supplementalFirst = '\U0001F000'
+	supplementalLast  = '\U0001FAFF'
+)
+
+// emojiPresentation lists the BMP code points that render as emoji by default,
+// even without a trailing variation selector.
+var emojiPresentation = [][2]rune{
+	{0x231A, 0x231B}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F},
+	{0x2693, 0x2693}, {0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE},
+	{0x26C4, 0x26C5}, {0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA},
+	{0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA}, {0x26FD, 0x26FD},
+	{0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+	{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+}
+
+// textPresentation lists the BMP pictographs that render as plain text unless
+// followed by U+FE0F, a skin tone modifier, or joined into a ZWJ sequence.
+var textPresentation = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x2328, 0x2328}, {0x23CF, 0x23CF}, {0x23E9, 0x23F3}, {0x23F8, 0x23FA},
+	{0x24C2, 0x24C2}, {0x25AA, 0x25AB}, {0x25B6, 0x25B6}, {0x25C0, 0x25C0},
+	{0x25FB, 0x25FE}, {0x2600, 0x27BF}, {0x2934, 0x2935}, {0x2B05, 0x2B07},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x3030, 0x3030},
+	{0x303D, 0x303D}, {0x3297, 0x3297}, {0x3299, 0x3299},
+}
+
+func inRanges(r rune, ranges [][2]rune) bool {
+	for _, rg := range ranges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isSkinTone(r rune) bool {
+	return r >= skinToneFirst && r <= skinToneLast
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalFirst && r <= regionalLast
+}
+
+func isKeycapBase(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '#' || r == '*'
+}
+
+func isDefaultEmoji(r rune) bool {
+	if r >= supplementalFirst && r <= supplementalLast {
+		return !isRegionalIndicator(r)
+	}
+	return inRanges(r, emojiPresentation)
+}
+
+func isPictographic(r rune) bool {
+	return isDefaultEmoji(r) || inRanges(r, textPresentation)
+}
+
+// ExtractEmojis returns every emoji in text as a full grapheme cluster, so ZWJ
+// sequences (👨‍👩‍👧), skin tone variants (👍🏽), flags (🇪🇹) and keycaps (1️⃣)
+// are each reported as a single emoji.
+func ExtractEmojis(text string) []string {
+	runes := []rune(text)
+	emojis := make([]string, 0)
+	for i := 0; i < len(runes); {
+		end, ok := emojiClusterEnd(runes, i)
+		if !ok {
+			i++
+			continue
+		}
+		emojis = append(emojis, string(runes[i:end]))
+		i = end
+	}
+	return emojis
+}
+
+// emojiClusterEnd reports whether an emoji cluster starts at runes[start] and,
+// if so, the index just past its last rune.
+func emojiClusterEnd(runes []rune, start int) (int, bool) {
+	r := runes[start]
+	switch {
+	case isRegionalIndicator(r):
+		if start+1 < len(runes) && isRegionalIndicator(runes[start+1]) {
+			return start + 2, true
+		}
+		return 0, false
+	case isKeycapBase(r):
+		i := start + 1
+		if i < len(runes) && runes[i] == variationEmoji {
+			i++
+		}
+		if i < len(runes) && runes[i] == combiningKeycap {
+			return i + 1, true
+		}
+		return 0, false
+	case !isPictographic(r):
+		return 0, false
+	}
+
+	i, emoji := pictographEnd(runes, start)
+	if !emoji {
+		return 0, false
+	}
+	for i+1 < len(runes) && runes[i] == zeroWidthJoiner && isPictographic(runes[i+1]) {
+		i, _ = pictographEnd(runes, i+1)
+	}
+	return i, true
+}
+
+// pictographEnd consumes a single pictograph with its presentation selector,
+// skin tone modifier and tag sequence, reporting whether it renders as emoji.
+func pictographEnd(runes []rune, start int) (int, bool) {
+	emoji := isDefaultEmoji(runes[start])
+	i := start + 1
+	if i < len(runes) {
+		switch runes[i] {
+		case variationEmoji:
+			emoji = true
+			i++
+		case variationText:
+			return i + 1, false
+		}
+	}
+	if i < len(runes) && isSkinTone(runes[i]) {
+		emoji = true
+		i++
+	}
+	if i < len(runes) && runes[i] >= tagFirst && runes[i] < tagCancel {
+		j := i
+		for j < len(runes) && runes[j] >= tagFirst && runes[j] < tagCancel {
+			j++
+		}
+		if j < len(runes) && runes[j] == tagCancel {
+			i = j + 1
+		}
+	}
+	if i < len(runes) && runes[i] == zeroWidthJoiner {
+		emoji = true
+	}
+	return i, emoji
+}
+
+// countEmojis tallies the emoji clusters found in text by type.
+func countEmojis(text string) (map[string]int, int) {
+	counter := make(map[string]int)
+	emojis := ExtractEmojis(text)
+	for _, e := range emojis {
+		counter[e] += 1
+	}
+	return counter, len(emojis)
+}
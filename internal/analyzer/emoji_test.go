@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractEmojis(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"plain text", "hello world 123", []string{}},
+		{"single", "great news 🎉", []string{"🎉"}},
+		{"skin tone", "👍🏽 thanks", []string{"👍🏽"}},
+		{"zwj family", "family 👨‍👩‍👧 time", []string{"👨‍👩‍👧"}},
+		{"zwj with skin tone", "👩🏾‍💻 coding", []string{"👩🏾‍💻"}},
+		{"flag", "🇪🇹 Ethiopia", []string{"🇪🇹"}},
+		{"keycap", "step 1️⃣ then #️⃣", []string{"1️⃣", "#️⃣"}},
+		{"variation selector", "❤️ and ❤", []string{"❤️"}},
+		{"text presentation", "copyright © 2025", []string{}},
+		{"emoji presentation default", "✅ done ⭐", []string{"✅", "⭐"}},
+		{"repeated", "🔥🔥🔥", []string{"🔥", "🔥", "🔥"}},
+	}
+
+	for _, tc := range cases {
+		got := ExtractEmojis(tc.text)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: ExtractEmojis(%q) = %q, want %q", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestCountEmojis(t *testing.T) {
+	counter, total := countEmojis("🔥 launch 🔥 👍🏽👍")
+	if total != 4 {
+		t.Fatalf("countEmojis total = %d, want 4", total)
+	}
+	want := map[string]int{"🔥": 2, "👍🏽": 1, "👍": 1}
+	if !reflect.DeepEqual(counter, want) {
+		t.Fatalf("countEmojis counter = %v, want %v", counter, want)
+	}
+}
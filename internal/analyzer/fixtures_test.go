@@ -0,0 +1,35 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotd/td/tg"
+)
+
+// closeTo compares derived ratios without depending on float rounding.
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
+
+// testAnalytics returns analytics over 2025 bucketed in timezone, UTC when
+// timezone is empty.
+func testAnalytics(t *testing.T, timezone string) *Analytics {
+	t.Helper()
+	window, err := YearWindow(2025).In(timezone)
+	if err != nil {
+		t.Fatalf("In(%q) returned error: %v", timezone, err)
+	}
+	a := NewAnalytics("test")
+	a.SetWindow(window)
+	return &a
+}
+
+// reactionsOf builds the reactions of a test post.
+func reactionsOf(results ...tg.ReactionCount) tg.MessageReactions {
+	return tg.MessageReactions{Results: results}
+}
+
+func emojiReaction(emoticon string, count int) tg.ReactionCount {
+	return tg.ReactionCount{Reaction: &tg.ReactionEmoji{Emoticon: emoticon}, Count: count}
+}
@@ -0,0 +1,282 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+const (
+	ForwardKindChannel = "channel"
+	ForwardKindUser    = "user"
+	ForwardKindChat    = "chat"
+	// ForwardKindHidden is a user who hides their account in forwards, only
+	// the name is known.
+	ForwardKindHidden = "hidden"
+
+	forwardProfileLimit = 20
+)
+
+type ForwardNode struct {
+	Key      string `json:"key"`
+	Kind     string `json:"kind"`
+	ID       int64  `json:"id,string"`
+	Title    string `json:"title"`
+	Username string `json:"username"`
+	Forwards int    `json:"forwards"`
+	Profile  string `json:"profile"`
+}
+
+type ForwardEdge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Forwards int    `json:"forwards"`
+}
+
+// ForwardGraph is the star shaped graph of every source the analyzed chat
+// forwarded from, each edge pointing at the chat itself. Sources are resolved
+// from the chats and users of every crawled batch, so a source is named even
+// when its forwards and its peer info arrive in different batches.
+type ForwardGraph struct {
+	Root  ForwardNode   `json:"root"`
+	Nodes []ForwardNode `json:"nodes"`
+	Edges []ForwardEdge `json:"edges"`
+
+	counts   map[string]*ForwardNode
+	channels map[int64]*tg.Channel
+	users    map[int64]*tg.User
+}
+
+func NewForwardGraph() ForwardGraph {
+	return ForwardGraph{
+		counts:   make(map[string]*ForwardNode),
+		channels: make(map[int64]*tg.Channel),
+		users:    make(map[int64]*tg.User),
+	}
+}
+
+func forwardKey(kind string, id int64, name string) string {
+	if kind == ForwardKindHidden {
+		return kind + ":" + name
+	}
+	return kind + ":" + strconv.FormatInt(id, 10)
+}
+
+func (g *ForwardGraph) UpdateForwards(msg *tg.Message) {
+	fwd, ok := msg.GetFwdFrom()
+	if !ok {
+		return
+	}
+	kind, id, name := "", int64(0), ""
+	if from, ok := fwd.GetFromID(); ok {
+		switch peer := from.(type) {
+		case *tg.PeerChannel:
+			kind, id = ForwardKindChannel, peer.ChannelID
+		case *tg.PeerUser:
+			kind, id = ForwardKindUser, peer.UserID
+		case *tg.PeerChat:
+			kind, id = ForwardKindChat, peer.ChatID
+		}
+	} else if fromName, ok := fwd.GetFromName(); ok {
+		kind, name = ForwardKindHidden, fromName
+	}
+	if kind == "" {
+		return
+	}
+
+	key := forwardKey(kind, id, name)
+	node, ok := g.counts[key]
+	if !ok {
+		node = &ForwardNode{Key: key, Kind: kind, ID: id, Title: name}
+		g.counts[key] = node
+	}
+	node.Forwards += 1
+}
+
+// UpdatePeers caches the chats and users of a batch that are forward sources.
+func (g *ForwardGraph) UpdatePeers(chats []tg.ChatClass, users []tg.UserClass) {
+	for _, chat := range chats {
+		switch c := chat.(type) {
+		case *tg.Channel:
+			if node, ok := g.counts[forwardKey(ForwardKindChannel, c.ID, "")]; ok {
+				node.Title, node.Username = c.Title, c.Username
+				g.channels[c.ID] = c
+			}
+		case *tg.Chat:
+			if node, ok := g.counts[forwardKey(ForwardKindChat, c.ID, "")]; ok {
+				node.Title = c.Title
+			}
+		}
+	}
+	for _, u := range users {
+		user, ok := u.(*tg.User)
+		if !ok {
+			continue
+		}
+		if node, ok := g.counts[forwardKey(ForwardKindUser, user.ID, "")]; ok {
+			node.Title = strings.TrimSpace(user.FirstName + " " + user.LastName)
+			node.Username = user.Username
+			g.users[user.ID] = user
+		}
+	}
+}
+
+// resolvedChannels returns every forwarded channel whose info was seen.
+func (g *ForwardGraph) resolvedChannels() []tg.ChatClass {
+	chats := make([]tg.ChatClass, 0, len(g.channels))
+	for _, c := range g.channels {
+		chats = append(chats, c)
+	}
+	return chats
+}
+
+// GetForwardGraph ranks the sources by forwards and links them to root.
+func (g *ForwardGraph) GetForwardGraph(root ForwardNode) {
+	root.Key = forwardKey(root.Kind, root.ID, "")
+	g.Root = root
+
+	nodes := make([]ForwardNode, 0, len(g.counts))
+	for _, node := range g.counts {
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Forwards == nodes[j].Forwards {
+			return nodes[i].Key < nodes[j].Key
+		}
+		return nodes[i].Forwards > nodes[j].Forwards
+	})
+	g.Nodes = nodes
+
+	g.Edges = make([]ForwardEdge, 0, len(nodes))
+	for _, node := range nodes {
+		g.Edges = append(g.Edges, ForwardEdge{Source: node.Key, Target: root.Key, Forwards: node.Forwards})
+	}
+}
+
+// ResolveForwardProfiles downloads the profile pictures of the most forwarded
+// sources. Failures are logged and leave the profile empty.
+func (ar *Analyzer) ResolveForwardProfiles(ctx context.Context, g *ForwardGraph) {
+	log := logger.With("operation", "ResolveForwardProfiles")
+
+	for i := range g.Nodes {
+		if i >= forwardProfileLimit || ctx.Err() != nil {
+			break
+		}
+		node := &g.Nodes[i]
+		var peer tg.InputPeerClass
+		var photoID int64
+		switch node.Kind {
+		case ForwardKindChannel:
+			c, ok := g.channels[node.ID]
+			if !ok {
+				continue
+			}
+			photo, ok := c.Photo.(*tg.ChatPhoto)
+			if !ok {
+				continue
+			}
+			peer, photoID = c.AsInputPeer(), photo.PhotoID
+		case ForwardKindUser:
+			u, ok := g.users[node.ID]
+			if !ok {
+				continue
+			}
+			photo, ok := u.Photo.(*tg.UserProfilePhoto)
+			if !ok {
+				continue
+			}
+			peer, photoID = &tg.InputPeerUser{UserID: u.ID, AccessHash: u.AccessHash}, photo.PhotoID
+		default:
+			continue
+		}
+
+		profile, err := ar.downloadPeerPhoto(ctx, peer, photoID, node.Kind, node.ID, node.Title)
+		if err != nil {
+			log.Warn("Failed to download forward source profile", "source", node.Key, "error", err)
+			continue
+		}
+		node.Profile = profile
+	}
+}
+
+// profile returns the profile ResolveForwardProfiles downloaded for a source,
+// empty when it has none. resolved is false when the source was not among the
+// ones it downloaded.
+func (g ForwardGraph) profile(kind string, id int64) (profile string, resolved bool) {
+	key := forwardKey(kind, id, "")
+	for i, node := range g.Nodes {
+		if node.Key == key {
+			return node.Profile, i < forwardProfileLimit
+		}
+	}
+	return "", false
+}
+
+// WriteGraphML writes the graph in GraphML for tools such as Gephi.
+func (g ForwardGraph) WriteGraphML(w io.Writer) error {
+	escape := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	node := func(n ForwardNode) string {
+		return fmt.Sprintf("    <node id=\"%s\">\n"+
+			"      <data key=\"title\">%s</data>\n"+
+			"      <data key=\"username\">%s</data>\n"+
+			"      <data key=\"kind\">%s</data>\n"+
+			"      <data key=\"profile\">%s</data>\n"+
+			"    </node>\n",
+			escape(n.Key), escape(n.Title), escape(n.Username), escape(n.Kind), escape(n.Profile))
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	for _, attr := range []string{"title", "username", "kind", "profile"} {
+		fmt.Fprintf(&b, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"string\"/>\n", attr, attr)
+	}
+	b.WriteString("  <key id=\"forwards\" for=\"edge\" attr.name=\"forwards\" attr.type=\"int\"/>\n")
+	b.WriteString("  <graph id=\"forwards\" edgedefault=\"directed\">\n")
+	b.WriteString(node(g.Root))
+	for _, n := range g.Nodes {
+		b.WriteString(node(n))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "    <edge source=\"%s\" target=\"%s\">\n      <data key=\"forwards\">%d</data>\n    </edge>\n",
+			escape(e.Source), escape(e.Target), e.Forwards)
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDOT writes the graph in Graphviz DOT, edge widths follow the number of
+// forwards.
+func (g ForwardGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph forwards {\n  rankdir=LR;\n")
+	fmt.Fprintf(&b, "  %s [label=%s, shape=doublecircle];\n", strconv.Quote(g.Root.Key), strconv.Quote(g.Root.Title))
+	for _, n := range g.Nodes {
+		label := n.Title
+		if label == "" {
+			label = n.Key
+		}
+		fmt.Fprintf(&b, "  %s [label=%s];\n", strconv.Quote(n.Key), strconv.Quote(label))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=\"%d\", penwidth=%d];\n",
+			strconv.Quote(e.Source), strconv.Quote(e.Target), e.Forwards, min(1+e.Forwards/10, 10))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gotd/td/tg"
+)
+
+func forwardedFrom(peer tg.PeerClass) *tg.Message {
+	msg := &tg.Message{}
+	fwd := tg.MessageFwdHeader{}
+	fwd.SetFromID(peer)
+	msg.SetFwdFrom(fwd)
+	return msg
+}
+
+func TestForwardGraph(t *testing.T) {
+	g := NewForwardGraph()
+
+	// The source is forwarded in one batch and only resolved in the next one
+	g.UpdateForwards(forwardedFrom(&tg.PeerChannel{ChannelID: 7}))
+	g.UpdatePeers(nil, nil)
+	g.UpdateForwards(forwardedFrom(&tg.PeerChannel{ChannelID: 7}))
+	g.UpdateForwards(forwardedFrom(&tg.PeerUser{UserID: 3}))
+	g.UpdatePeers([]tg.ChatClass{&tg.Channel{ID: 7, Title: "Source & Co", Username: "source"}}, nil)
+
+	g.GetForwardGraph(ForwardNode{Kind: ForwardKindChannel, ID: 1, Title: "Root"})
+
+	if len(g.Nodes) != 2 || g.Nodes[0].Key != "channel:7" || g.Nodes[0].Forwards != 2 || g.Nodes[0].Title != "Source & Co" {
+		t.Fatalf("nodes = %+v, want channel:7 first with 2 forwards and its title", g.Nodes)
+	}
+	if len(g.Edges) != 2 || g.Edges[0].Target != "channel:1" {
+		t.Fatalf("edges = %+v, want every source linked to channel:1", g.Edges)
+	}
+
+	var graphML strings.Builder
+	if err := g.WriteGraphML(&graphML); err != nil {
+		t.Fatalf("WriteGraphML returned error: %v", err)
+	}
+	if !strings.Contains(graphML.String(), "Source &amp; Co") {
+		t.Fatalf("GraphML does not escape titles:\n%s", graphML.String())
+	}
+
+	var dot strings.Builder
+	if err := g.WriteDOT(&dot); err != nil {
+		t.Fatalf("WriteDOT returned error: %v", err)
+	}
+	if !strings.Contains(dot.String(), `"channel:7" -> "channel:1"`) {
+		t.Fatalf("DOT is missing the channel:7 edge:\n%s", dot.String())
+	}
+}
+
+func TestForwardGraphProfile(t *testing.T) {
+	g := NewForwardGraph()
+	g.UpdateForwards(forwardedFrom(&tg.PeerChannel{ChannelID: 7}))
+	g.UpdateForwards(forwardedFrom(&tg.PeerChannel{ChannelID: 7}))
+	g.UpdateForwards(forwardedFrom(&tg.PeerUser{UserID: 7}))
+	for id := int64(100); id < 100+forwardProfileLimit; id++ {
+		g.UpdateForwards(forwardedFrom(&tg.PeerChannel{ChannelID: id}))
+	}
+	g.GetForwardGraph(ForwardNode{Kind: ForwardKindChannel, ID: 1})
+	for i := range g.Nodes {
+		g.Nodes[i].Profile = "/profiles/" + strings.ReplaceAll(g.Nodes[i].Key, ":", "-") + ".jpg"
+	}
+
+	// A user and a channel sharing an ID keep their own profile
+	if profile, resolved := g.profile(ForwardKindChannel, 7); profile != "/profiles/channel-7.jpg" || !resolved {
+		t.Fatalf("channel 7 profile = %q %v, want its own", profile, resolved)
+	}
+	if profile, _ := g.profile(ForwardKindUser, 7); profile != "/profiles/user-7.jpg" {
+		t.Fatalf("user 7 profile = %q, want its own", profile)
+	}
+	if _, resolved := g.profile(ForwardKindChannel, 42); resolved {
+		t.Fatalf("expected an unknown source not to be resolved")
+	}
+
+	past := g.Nodes[len(g.Nodes)-1]
+	if len(g.Nodes) <= forwardProfileLimit {
+		t.Fatalf("expected more sources than the profile limit, got %d", len(g.Nodes))
+	}
+	if _, resolved := g.profile(past.Kind, past.ID); resolved {
+		t.Fatalf("expected %s past the profile limit not to be resolved", past.Key)
+	}
+}
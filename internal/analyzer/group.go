@@ -0,0 +1,315 @@
+package analyzer
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+const (
+	ModeChannel = "channel"
+	ModeGroup   = "group"
+
+	topPosterLimit  = 10
+	retentionPeriod = 30 * 24 * time.Hour
+)
+
+type PosterStats struct {
+	UserID   int64  `json:"user_id,string"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Messages int    `json:"messages"`
+	Replies  int    `json:"replies"`
+}
+
+type groupReply struct {
+	from      int64
+	toMessage int
+}
+
+type groupMember struct {
+	name     string
+	username string
+}
+
+// GroupStats describes the members of a supergroup or basic group rather than
+// the posts of a channel.
+type GroupStats struct {
+	Members                 int            `json:"members"`
+	ActiveMembers           int            `json:"active_members"`
+	TotalMessages           int            `json:"total_messages"`
+	MessagesPerMember       float64        `json:"messages_per_member"`
+	MessagesPerActiveMember float64        `json:"messages_per_active_member"`
+	TopPosters              []PosterStats  `json:"top_posters"`
+	ActiveMembersByMonth    map[string]int `json:"active_members_by_month"`
+	JoinsByMonth            map[string]int `json:"joins_by_month"`
+	LeavesByMonth           map[string]int `json:"leaves_by_month"`
+	ReplyEdges              int            `json:"reply_edges"`
+	ReplyGraphDensity       float64        `json:"reply_graph_density"`
+	Newcomers               int            `json:"newcomers"`
+	NewcomerPostedRatio     float64        `json:"newcomer_posted_ratio"`
+	NewcomerRetention30d    float64        `json:"newcomer_retention_30d"`
+
+	messagesByUser map[int64]int
+	repliesByUser  map[int64]int
+	authorOf       map[int]int64
+	replies        []groupReply
+	activeByMonth  map[string]map[int64]bool
+	lastPostAt     map[int64]int
+	joinedAt       map[int64]int
+	members        map[int64]groupMember
+	location       *time.Location
+}
+
+func NewGroupStats() *GroupStats {
+	return &GroupStats{
+		ActiveMembersByMonth: make(map[string]int),
+		JoinsByMonth:         make(map[string]int),
+		LeavesByMonth:        make(map[string]int),
+		messagesByUser:       make(map[int64]int),
+		repliesByUser:        make(map[int64]int),
+		authorOf:             make(map[int]int64),
+		activeByMonth:        make(map[string]map[int64]bool),
+		lastPostAt:           make(map[int64]int),
+		joinedAt:             make(map[int64]int),
+		members:              make(map[int64]groupMember),
+	}
+}
+
+// monthKey buckets a message date in the window timezone like the other
+// sections, UTC when none is set.
+func (g *GroupStats) monthKey(date int) string {
+	dateTime := getDateTime(date)
+	if g.location != nil {
+		dateTime = dateTime.In(g.location)
+	}
+	return MonthKey(dateTime)
+}
+
+func (g *GroupStats) UpdateMembers(users []tg.UserClass) {
+	for _, u := range users {
+		user, ok := u.(*tg.User)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		g.members[user.ID] = groupMember{name: name, username: user.Username}
+	}
+}
+
+func (g *GroupStats) UpdateGroup(msg *tg.Message) {
+	from, ok := msg.FromID.(*tg.PeerUser)
+	if !ok {
+		return
+	}
+	userID := from.UserID
+	g.TotalMessages += 1
+	g.messagesByUser[userID] += 1
+	g.authorOf[msg.ID] = userID
+	if msg.Date > g.lastPostAt[userID] {
+		g.lastPostAt[userID] = msg.Date
+	}
+
+	month := g.monthKey(msg.Date)
+	if g.activeByMonth[month] == nil {
+		g.activeByMonth[month] = make(map[int64]bool)
+	}
+	g.activeByMonth[month][userID] = true
+
+	if reply, ok := msg.ReplyTo.(*tg.MessageReplyHeader); ok && reply.ReplyToMsgID != 0 {
+		g.repliesByUser[userID] += 1
+		g.replies = append(g.replies, groupReply{from: userID, toMessage: reply.ReplyToMsgID})
+	}
+}
+
+// UpdateMembership counts join and leave service messages. The earliest join
+// inside the window is kept for newcomer retention.
+func (g *GroupStats) UpdateMembership(msg *tg.MessageService) {
+	month := g.monthKey(msg.Date)
+	joined := make([]int64, 0)
+	switch action := msg.Action.(type) {
+	case *tg.MessageActionChatAddUser:
+		joined = append(joined, action.Users...)
+	case *tg.MessageActionChatJoinedByLink, *tg.MessageActionChatJoinedByRequest:
+		if from, ok := msg.FromID.(*tg.PeerUser); ok {
+			joined = append(joined, from.UserID)
+		}
+	case *tg.MessageActionChatDeleteUser:
+		g.LeavesByMonth[month] += 1
+		return
+	default:
+		return
+	}
+
+	for _, userID := range joined {
+		g.JoinsByMonth[month] += 1
+		if at, ok := g.joinedAt[userID]; !ok || msg.Date < at {
+			g.joinedAt[userID] = msg.Date
+		}
+	}
+}
+
+// GetGroupStats derives the member level metrics once the crawl is complete.
+// windowEnd bounds the retention check so newcomers who joined less than the
+// retention period ago are not counted as churned.
+func (g *GroupStats) GetGroupStats(members int, windowEnd int) {
+	g.Members = members
+	g.ActiveMembers = len(g.messagesByUser)
+	g.MessagesPerMember = ratio(g.TotalMessages, members)
+	g.MessagesPerActiveMember = ratio(g.TotalMessages, g.ActiveMembers)
+
+	for month, active := range g.activeByMonth {
+		g.ActiveMembersByMonth[month] = len(active)
+	}
+
+	posters := make([]PosterStats, 0, len(g.messagesByUser))
+	for userID, cnt := range g.messagesByUser {
+		member := g.members[userID]
+		posters = append(posters, PosterStats{
+			UserID:   userID,
+			Name:     member.name,
+			Username: member.username,
+			Messages: cnt,
+			Replies:  g.repliesByUser[userID],
+		})
+	}
+	sort.Slice(posters, func(i, j int) bool {
+		if posters[i].Messages == posters[j].Messages {
+			return posters[i].UserID < posters[j].UserID
+		}
+		return posters[i].Messages > posters[j].Messages
+	})
+	if len(posters) > topPosterLimit {
+		posters = posters[:topPosterLimit]
+	}
+	g.TopPosters = posters
+
+	// Directed reply graph between members, self replies are not edges
+	edges := make(map[[2]int64]bool)
+	for _, r := range g.replies {
+		to, ok := g.authorOf[r.toMessage]
+		if !ok || to == r.from {
+			continue
+		}
+		edges[[2]int64{r.from, to}] = true
+	}
+	g.ReplyEdges = len(edges)
+	if n := g.ActiveMembers; n > 1 {
+		g.ReplyGraphDensity = float64(len(edges)) / float64(n*(n-1))
+	}
+
+	g.Newcomers = len(g.joinedAt)
+	posted, retained, eligible := 0, 0, 0
+	retentionSeconds := int(retentionPeriod.Seconds())
+	for userID, joinedAt := range g.joinedAt {
+		last, ok := g.lastPostAt[userID]
+		if ok && last >= joinedAt {
+			posted += 1
+		}
+		if joinedAt+retentionSeconds > windowEnd {
+			continue
+		}
+		eligible += 1
+		if ok && last >= joinedAt+retentionSeconds {
+			retained += 1
+		}
+	}
+	g.NewcomerPostedRatio = ratio(posted, g.Newcomers)
+	g.NewcomerRetention30d = ratio(retained, eligible)
+}
+
+func (a *Analytics) updateFromGroupMessages(m tg.ModifiedMessagesMessages) int {
+	if m == nil {
+		return 0
+	}
+	a.Group.UpdateMembers(m.GetUsers())
+	defer a.Forwards.UpdatePeers(m.GetChats(), m.GetUsers())
+
+	offSet := 0
+	minDateUnix := a.Window.startUnix()
+	maxDateUnix := a.Window.endUnix()
+	for _, msg := range m.GetMessages() {
+		notEmpty, ok := msg.AsNotEmpty()
+		if !ok || a.seenMessage(notEmpty.GetID()) {
+			continue
+		}
+		offSet = notEmpty.GetDate()
+		if offSet <= minDateUnix {
+			return minDateUnix
+		}
+		if offSet >= maxDateUnix {
+			continue
+		}
+		a.Edits.UpdateMessageID(notEmpty.GetID(), offSet)
+		switch mm := msg.(type) {
+		case *tg.Message:
+			a.updateFromMessage(mm)
+			a.Group.UpdateGroup(mm)
+		case *tg.MessageService:
+			a.Group.UpdateMembership(mm)
+		}
+	}
+	return offSet
+}
+
+// processGroup crawls a supergroup or basic group and fills in the group
+// section alongside the metrics shared with channels.
+func (ar *Analyzer) processGroup(ctx context.Context, a *Analytics, chat tg.ChatClass) error {
+	log := logger.With("operation", "processGroup", "chat_id", chat.GetID())
+	api := ar.client.API()
+
+	a.Mode = ModeGroup
+	a.Group = NewGroupStats()
+	a.Group.location = a.Window.Location()
+
+	members := 0
+	var peer tg.InputPeerClass
+	root := ForwardNode{Kind: ForwardKindChat, ID: chat.GetID()}
+	switch c := chat.(type) {
+	case *tg.Channel:
+		peer = c.AsInputPeer()
+		root = ForwardNode{Kind: ForwardKindChannel, ID: c.ID, Title: c.Title, Username: c.Username}
+		a.ChannelProfile = ar.downloadProfileOrWarn(ctx, c, log)
+		info, err := ar.GetChannelInfo(ctx, api, c)
+		if err != nil {
+			log.Warn("Failed to fetch group info, continuing without it", "error", err)
+		}
+		a.Info = info
+		members = info.Subscribers
+	case *tg.Chat:
+		peer = &tg.InputPeerChat{ChatID: c.ID}
+		root.Title = c.Title
+		// Basic group message IDs are shared with the account's private chats,
+		// gaps between them say nothing about deletions
+		a.Edits.skipDeletions = true
+		members = c.ParticipantsCount
+		a.Info = ChannelInfo{
+			ID:          c.ID,
+			Subscribers: c.ParticipantsCount,
+			CapturedAt:  time.Now().UTC(),
+		}
+		if photo, ok := c.Photo.(*tg.ChatPhoto); ok {
+			profile, err := ar.downloadPeerPhoto(ctx, peer, photo.PhotoID, ForwardKindChat, c.ID, c.Title)
+			if err != nil {
+				log.Warn("Failed to download group profile, continuing without it", "error", err)
+			} else {
+				a.ChannelProfile = profile
+			}
+		}
+	}
+
+	log.Info("Fetching group messages", "start_date", a.Window.Start.Format(windowDateLayout))
+	if _, err := ar.crawlHistory(ctx, api, peer, a.ChannelName, a.Window, log, a.updateFromGroupMessages); err != nil {
+		return err
+	}
+
+	a.Group.GetGroupStats(members, a.Window.endUnix())
+	root.Profile = a.ChannelProfile
+	a.Forwards.GetForwardGraph(root)
+	ar.ResolveForwardProfiles(ctx, &a.Forwards)
+	return nil
+}
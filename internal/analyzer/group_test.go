@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+var groupDay0 = int(time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC).Unix())
+
+func groupDay(n int) int {
+	return groupDay0 + n*24*60*60
+}
+
+func groupMessage(id int, from int64, date, replyTo int) *tg.Message {
+	msg := &tg.Message{ID: id, FromID: &tg.PeerUser{UserID: from}, Date: date}
+	if replyTo != 0 {
+		msg.ReplyTo = &tg.MessageReplyHeader{ReplyToMsgID: replyTo}
+	}
+	return msg
+}
+
+func groupJoin(from int64, date int) *tg.MessageService {
+	return &tg.MessageService{
+		FromID: &tg.PeerUser{UserID: from},
+		Date:   date,
+		Action: &tg.MessageActionChatJoinedByLink{},
+	}
+}
+
+func TestGetGroupStats(t *testing.T) {
+	cases := []struct {
+		name       string
+		members    int
+		windowEnd  int
+		messages   []*tg.Message
+		joins      []*tg.MessageService
+		posters    []int64
+		edges      int
+		density    float64
+		perMember  float64
+		newcomers  int
+		posted     float64
+		retained30 float64
+	}{
+		{
+			name:      "empty",
+			windowEnd: groupDay(60),
+			posters:   []int64{},
+		},
+		{
+			name:      "top posters and reply graph",
+			members:   10,
+			windowEnd: groupDay(60),
+			messages: []*tg.Message{
+				groupMessage(1, 1, groupDay(0), 0),
+				groupMessage(2, 1, groupDay(0), 1),
+				groupMessage(3, 2, groupDay(1), 1),
+				groupMessage(4, 2, groupDay(1), 2),
+				groupMessage(5, 3, groupDay(2), 1),
+				groupMessage(6, 1, groupDay(2), 99),
+			},
+			// 1 replies to itself and to a message outside the crawl, 2 replies
+			// to 1 twice, 3 replies to 1 once: two edges of the six possible
+			posters:   []int64{1, 2, 3},
+			edges:     2,
+			density:   2.0 / 6.0,
+			perMember: 0.6,
+		},
+		{
+			name:      "ties broken by user id",
+			members:   2,
+			windowEnd: groupDay(60),
+			messages: []*tg.Message{
+				groupMessage(1, 8, groupDay(0), 0),
+				groupMessage(2, 5, groupDay(0), 1),
+			},
+			posters:   []int64{5, 8},
+			edges:     1,
+			density:   0.5,
+			perMember: 1,
+		},
+		{
+			name:      "newcomer retention",
+			members:   5,
+			windowEnd: groupDay(60),
+			messages: []*tg.Message{
+				groupMessage(1, 4, groupDay(1), 0),
+				groupMessage(2, 4, groupDay(31), 0),
+				groupMessage(3, 5, groupDay(1), 0),
+				groupMessage(4, 7, groupDay(2), 0),
+			},
+			// 4 is retained, 5 posted but stopped, 6 joined too recently to
+			// count and 7 never joined inside the window
+			joins: []*tg.MessageService{
+				groupJoin(4, groupDay(0)),
+				groupJoin(5, groupDay(0)),
+				groupJoin(6, groupDay(50)),
+			},
+			posters:    []int64{4, 5, 7},
+			perMember:  0.8,
+			newcomers:  3,
+			posted:     2.0 / 3.0,
+			retained30: 0.5,
+		},
+	}
+
+	for _, tc := range cases {
+		g := NewGroupStats()
+		for _, msg := range tc.messages {
+			g.UpdateGroup(msg)
+		}
+		for _, msg := range tc.joins {
+			g.UpdateMembership(msg)
+		}
+		g.GetGroupStats(tc.members, tc.windowEnd)
+
+		posters := make([]int64, 0, len(g.TopPosters))
+		for _, p := range g.TopPosters {
+			posters = append(posters, p.UserID)
+		}
+		if !reflect.DeepEqual(posters, tc.posters) {
+			t.Fatalf("%s: top posters = %v, want %v", tc.name, posters, tc.posters)
+		}
+		if g.ReplyEdges != tc.edges || g.ReplyGraphDensity != tc.density {
+			t.Fatalf("%s: reply edges = %d (density %v), want %d (%v)", tc.name, g.ReplyEdges, g.ReplyGraphDensity, tc.edges, tc.density)
+		}
+		if g.MessagesPerMember != tc.perMember {
+			t.Fatalf("%s: messages per member = %v, want %v", tc.name, g.MessagesPerMember, tc.perMember)
+		}
+		if g.Newcomers != tc.newcomers || g.NewcomerPostedRatio != tc.posted || g.NewcomerRetention30d != tc.retained30 {
+			t.Fatalf("%s: newcomers = %d (posted %v, retained %v), want %d (%v, %v)",
+				tc.name, g.Newcomers, g.NewcomerPostedRatio, g.NewcomerRetention30d, tc.newcomers, tc.posted, tc.retained30)
+		}
+	}
+}
+
+func TestGroupStatsMonthsInWindowTimezone(t *testing.T) {
+	window, err := YearWindow(2025).In("Africa/Addis_Ababa")
+	if err != nil {
+		t.Fatalf("In returned error: %v", err)
+	}
+	g := NewGroupStats()
+	g.location = window.Location()
+
+	// 22:00 UTC on January 31st is already February in Addis Ababa
+	lateJanuary := int(time.Date(2025, time.January, 31, 22, 0, 0, 0, time.UTC).Unix())
+	g.UpdateGroup(groupMessage(1, 1, lateJanuary, 0))
+	g.UpdateMembership(groupJoin(2, lateJanuary))
+	g.GetGroupStats(2, window.endUnix())
+
+	if g.ActiveMembersByMonth["2025-February"] != 1 || g.JoinsByMonth["2025-February"] != 1 {
+		t.Fatalf("active by month = %v, joins by month = %v, want both in 2025-February", g.ActiveMembersByMonth, g.JoinsByMonth)
+	}
+}
+
+func TestMessagePagesOverlap(t *testing.T) {
+	page := func(ids ...int) *tg.MessagesMessages {
+		m := &tg.MessagesMessages{}
+		for _, id := range ids {
+			m.Messages = append(m.Messages, groupMessage(id, int64(id%2+1), groupDay(id), 0))
+		}
+		return m
+	}
+
+	// The second page starts with the last message of the first one
+	group := testAnalytics(t, "")
+	group.Group = NewGroupStats()
+	group.updateFromGroupMessages(page(5, 4, 3))
+	group.updateFromGroupMessages(page(3, 2, 1))
+
+	channel := testAnalytics(t, "")
+	channel.updateFromChannelMessages(page(5, 4, 3))
+	channel.updateFromChannelMessages(page(3, 2, 1))
+
+	if group.Group.TotalMessages != 5 {
+		t.Fatalf("group messages = %d, want 5", group.Group.TotalMessages)
+	}
+	if group.Totals.TotalPosts != 5 || channel.Totals.TotalPosts != 5 {
+		t.Fatalf("group posts = %d, channel posts = %d, want 5 each", group.Totals.TotalPosts, channel.Totals.TotalPosts)
+	}
+}
@@ -1,7 +1,11 @@
 package analyzer
 
 import (
+	"fmt"
+	"math"
+	"sort"
 	"time"
+	"unicode/utf16"
 
 	"github.com/gotd/td/tg"
 )
@@ -11,21 +15,47 @@ func getDateTime(date int) time.Time {
 	return t
 }
 
-func countNumOfReactions(reactions tg.MessageReactions) (map[string]int, int) {
-	counter := make(map[string]int)
-	// * Important: I am counting the custom reactions to total counter
-	totalCount := 0
+// MonthKey formats the month buckets used across the analytics, e.g.
+// "2025-January".
+func MonthKey(t time.Time) string {
+	return fmt.Sprintf("%d-%s", t.Year(), t.Month().String())
+}
+
+// ParseMonthKey returns the first day of the month of a month bucket key, in
+// UTC.
+func ParseMonthKey(key string) (time.Time, error) {
+	return time.Parse("2006-January", key)
+}
+
+type reactionCounts struct {
+	byEmoji       map[string]int
+	byCustomEmoji map[int64]int
+	paidStars     int
+	total         int
+}
+
+func countNumOfReactions(reactions tg.MessageReactions) reactionCounts {
+	counts := reactionCounts{
+		byEmoji:       make(map[string]int),
+		byCustomEmoji: make(map[int64]int),
+	}
+	// * Important: I am counting the custom reactions to total counter, paid
+	// reactions are counted in stars so they are kept out of it
 	for _, r := range reactions.Results {
-		totalCount += r.Count
-		switch r.Reaction.(type) {
+		switch reaction := r.Reaction.(type) {
 		case *tg.ReactionEmoji:
-			emojiReaction := r.Reaction.(*tg.ReactionEmoji)
-			counter[emojiReaction.Emoticon] += r.Count
+			counts.byEmoji[reaction.Emoticon] += r.Count
+			counts.total += r.Count
+		case *tg.ReactionCustomEmoji:
+			counts.byCustomEmoji[reaction.DocumentID] += r.Count
+			counts.total += r.Count
+		case *tg.ReactionPaid:
+			counts.paidStars += r.Count
 		default:
-			continue
+			counts.total += r.Count
 		}
 	}
-	return counter, totalCount
+	return counts
 }
 func mergeMaps(firstMap, secondMap map[string]int) map[string]int {
 	for key, val := range secondMap {
@@ -33,3 +63,144 @@ func mergeMaps(firstMap, secondMap map[string]int) map[string]int {
 	}
 	return firstMap
 }
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile returns the p-th percentile (0-100) of values using linear
+// interpolation between the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func median(values []float64) float64 {
+	return percentile(values, 50)
+}
+
+// pearson returns the Pearson correlation coefficient of xs and ys, 0 when
+// either series is constant or they are shorter than two values.
+func pearson(xs, ys []float64) float64 {
+	if len(xs) != len(ys) || len(xs) < 2 {
+		return 0
+	}
+	meanX, meanY := mean(xs), mean(ys)
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+// rankKeys returns up to limit keys of counter ordered by descending count,
+// ties broken alphabetically so the result is stable.
+func rankKeys(counter map[string]int, limit int) []string {
+	keys := make([]string, 0, len(counter))
+	for key := range counter {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counter[keys[i]] == counter[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return counter[keys[i]] > counter[keys[j]]
+	})
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+// entityText returns the text covered by a message entity. Entity offsets and
+// lengths are counted in UTF-16 code units.
+func entityText(text string, offset, length int) string {
+	units := utf16.Encode([]rune(text))
+	if offset < 0 || length <= 0 || offset+length > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[offset : offset+length]))
+}
+
+// mediaType classifies a post by its attached media, posts without media are
+// plain text.
+func mediaType(msg *tg.Message) string {
+	switch media := msg.Media.(type) {
+	case nil:
+		return "text"
+	case *tg.MessageMediaPhoto:
+		return "photo"
+	case *tg.MessageMediaWebPage:
+		return "link"
+	case *tg.MessageMediaPoll:
+		return "poll"
+	case *tg.MessageMediaGeo, *tg.MessageMediaGeoLive, *tg.MessageMediaVenue:
+		return "location"
+	case *tg.MessageMediaContact:
+		return "contact"
+	case *tg.MessageMediaDocument:
+		doc, ok := media.Document.(*tg.Document)
+		if !ok {
+			return "document"
+		}
+		return documentType(doc)
+	default:
+		return "other"
+	}
+}
+
+func documentType(doc *tg.Document) string {
+	kind := "document"
+	for _, attr := range doc.Attributes {
+		switch attribute := attr.(type) {
+		case *tg.DocumentAttributeSticker:
+			return "sticker"
+		case *tg.DocumentAttributeAnimated:
+			return "gif"
+		case *tg.DocumentAttributeVideo:
+			if attribute.RoundMessage {
+				kind = "video_note"
+			} else {
+				kind = "video"
+			}
+		case *tg.DocumentAttributeAudio:
+			if attribute.Voice {
+				kind = "voice"
+			} else {
+				kind = "audio"
+			}
+		}
+	}
+	return kind
+}
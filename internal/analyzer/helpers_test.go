@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestCountNumOfReactions(t *testing.T) {
+	reactions := reactionsOf(
+		emojiReaction("❤️", 10),
+		emojiReaction("👍", 5),
+		tg.ReactionCount{Reaction: &tg.ReactionCustomEmoji{DocumentID: 42}, Count: 3},
+		tg.ReactionCount{Reaction: &tg.ReactionCustomEmoji{DocumentID: 7}, Count: 2},
+		tg.ReactionCount{Reaction: &tg.ReactionPaid{}, Count: 2500},
+	)
+	counts := countNumOfReactions(reactions)
+
+	if want := map[string]int{"❤️": 10, "👍": 5}; !reflect.DeepEqual(counts.byEmoji, want) {
+		t.Fatalf("by emoji = %v, want %v", counts.byEmoji, want)
+	}
+	if want := map[int64]int{42: 3, 7: 2}; !reflect.DeepEqual(counts.byCustomEmoji, want) {
+		t.Fatalf("by custom emoji = %v, want %v", counts.byCustomEmoji, want)
+	}
+	// Paid reactions are worth stars, not reactions, and stay out of the total
+	if counts.paidStars != 2500 {
+		t.Fatalf("paid stars = %d, want 2500", counts.paidStars)
+	}
+	if counts.total != 20 {
+		t.Fatalf("total = %d, want 20 without paid stars", counts.total)
+	}
+
+	empty := countNumOfReactions(tg.MessageReactions{})
+	if empty.total != 0 || empty.paidStars != 0 || len(empty.byEmoji) != 0 || len(empty.byCustomEmoji) != 0 {
+		t.Fatalf("empty reactions = %+v, want nothing counted", empty)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{40, 10, 30, 20}
+	cases := []struct {
+		name   string
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 50, 0},
+		{"single value", []float64{7}, 90, 7},
+		{"lowest", values, 0, 10},
+		{"highest", values, 100, 40},
+		{"exact rank", []float64{3, 1, 2}, 50, 2},
+		{"interpolated median", values, 50, 25},
+		{"interpolated p90", values, 90, 37},
+	}
+
+	for _, tc := range cases {
+		if got := percentile(tc.values, tc.p); !closeTo(got, tc.want) {
+			t.Fatalf("%s: percentile(%v, %v) = %v, want %v", tc.name, tc.values, tc.p, got, tc.want)
+		}
+	}
+	if got := median(values); got != 25 {
+		t.Fatalf("median(%v) = %v, want 25", values, got)
+	}
+	if !reflect.DeepEqual(values, []float64{40, 10, 30, 20}) {
+		t.Fatalf("percentile sorted its input in place: %v", values)
+	}
+}
+
+func TestGetEngagementStats(t *testing.T) {
+	posts := []*tg.Message{
+		{
+			ID:        1,
+			Views:     40,
+			Replies:   tg.MessageReplies{Replies: 2},
+			Reactions: reactionsOf(emojiReaction("👍", 8)),
+		},
+		{
+			ID:        2,
+			Views:     40,
+			Forwards:  10,
+			Reactions: reactionsOf(emojiReaction("❤️", 10)),
+		},
+		// Posts without views have no engagement rate
+		{ID: 3},
+	}
+	analytics := func() *Analytics {
+		a := NewAnalytics("test")
+		for _, msg := range posts {
+			a.Totals.UpdateMetrics(msg)
+			a.Engagement.UpdateEngagement(msg)
+		}
+		return &a
+	}
+
+	a := analytics()
+	a.GetEngagementStats(0)
+	e := a.Engagement
+	if e.TotalEngagements != 30 || e.TotalShares != 10 {
+		t.Fatalf("engagements = %d, shares = %d, want 30 and 10", e.TotalEngagements, e.TotalShares)
+	}
+	if !closeTo(e.EngagementRate, 0.375) || !closeTo(e.ReactionsPer1kViews, 225) {
+		t.Fatalf("engagement rate = %v, reactions per 1k views = %v, want 0.375 and 225", e.EngagementRate, e.ReactionsPer1kViews)
+	}
+	if !closeTo(e.PostEngagementRate.Mean, 0.375) || !closeTo(e.PostEngagementRate.Median, 0.375) || !closeTo(e.PostEngagementRate.P90, 0.475) {
+		t.Fatalf("post engagement rate = %+v, want mean and median 0.375, p90 0.475", e.PostEngagementRate)
+	}
+	if e.Subscribers != 0 || e.EngagementPerSubscriber != 0 || e.PostEngagementPerSub != (Distribution{}) {
+		t.Fatalf("without subscribers got %d subscribers, %v per subscriber and %+v per post", e.Subscribers, e.EngagementPerSubscriber, e.PostEngagementPerSub)
+	}
+
+	a = analytics()
+	a.GetEngagementStats(8)
+	e = a.Engagement
+	if e.Subscribers != 8 || !closeTo(e.EngagementPerSubscriber, 3.75) {
+		t.Fatalf("subscribers = %d, engagement per subscriber = %v, want 8 and 3.75", e.Subscribers, e.EngagementPerSubscriber)
+	}
+	if !closeTo(e.PostEngagementPerSub.Mean, 1.25) || !closeTo(e.PostEngagementPerSub.Median, 1.25) || !closeTo(e.PostEngagementPerSub.P90, 2.25) {
+		t.Fatalf("post engagement per subscriber = %+v, want mean and median 1.25, p90 2.25", e.PostEngagementPerSub)
+	}
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/langid"
+)
+
+// LanguageBreakdown splits the posts with text by their detected language,
+// see langid for the supported languages.
+type LanguageBreakdown struct {
+	PostsByLanguage        map[string]int            `json:"posts_by_language"`
+	Share                  map[string]float64        `json:"share"`
+	ViewsByLanguage        map[string]int            `json:"views_by_language"`
+	AverageViewsByLanguage map[string]float64        `json:"average_views_by_language"`
+	PostsByMonth           map[string]map[string]int `json:"posts_by_month"`
+
+	location *time.Location
+	detected int
+}
+
+func NewLanguageBreakdown() LanguageBreakdown {
+	return LanguageBreakdown{
+		PostsByLanguage:        make(map[string]int),
+		Share:                  make(map[string]float64),
+		ViewsByLanguage:        make(map[string]int),
+		AverageViewsByLanguage: make(map[string]float64),
+		PostsByMonth:           make(map[string]map[string]int),
+	}
+}
+
+func (l *LanguageBreakdown) UpdateLanguages(msg *tg.Message) {
+	if msg.Message == "" {
+		return
+	}
+	language := langid.Detect(msg.Message).Language
+	dateTime := getDateTime(msg.Date)
+	if l.location != nil {
+		dateTime = dateTime.In(l.location)
+	}
+	month := MonthKey(dateTime)
+
+	l.detected += 1
+	l.PostsByLanguage[language] += 1
+	l.ViewsByLanguage[language] += msg.Views
+	if l.PostsByMonth[month] == nil {
+		l.PostsByMonth[month] = make(map[string]int)
+	}
+	l.PostsByMonth[month][language] += 1
+}
+
+// GetLanguageStats derives the shares and averages once the crawl is complete.
+func (l *LanguageBreakdown) GetLanguageStats() {
+	for language, posts := range l.PostsByLanguage {
+		l.Share[language] = ratio(posts, l.detected)
+		l.AverageViewsByLanguage[language] = ratio(l.ViewsByLanguage[language], posts)
+	}
+}
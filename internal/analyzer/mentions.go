@@ -0,0 +1,216 @@
+package analyzer
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+// Referrer is a watched channel or group that shared the target channel.
+// Each kind counts posts, a post that both forwards and links is counted in
+// both but only once in References and Reach.
+type Referrer struct {
+	ID              int64     `json:"id,string"`
+	Title           string    `json:"title"`
+	Username        string    `json:"username"`
+	Forwards        int       `json:"forwards"`
+	Links           int       `json:"links"`
+	Mentions        int       `json:"mentions"`
+	References      int       `json:"references"`
+	Reach           int       `json:"reach"`
+	LastReferenceAt time.Time `json:"last_reference_at"`
+}
+
+type MentionReport struct {
+	Target          string            `json:"target"`
+	Window          Window            `json:"window"`
+	ChannelsScanned int               `json:"channels_scanned"`
+	MessagesScanned int               `json:"messages_scanned"`
+	Referrers       []Referrer        `json:"referrers"`
+	Errors          map[string]string `json:"errors,omitempty"`
+}
+
+// mentionMatcher recognizes references to a single target channel. Mentions
+// and links need a public username, a private channel is only found through
+// forwards.
+type mentionMatcher struct {
+	channelID int64
+	mention   string
+	link      *regexp.Regexp
+}
+
+func newMentionMatcher(target *tg.Channel) mentionMatcher {
+	mm := mentionMatcher{channelID: target.ID}
+	if target.Username != "" {
+		mm.mention = "@" + strings.ToLower(target.Username)
+		mm.link = regexp.MustCompile(`(?i)(?:^|[/.])(?:t|telegram)\.me/(?:s/)?` + regexp.QuoteMeta(target.Username) + `(?:$|[^A-Za-z0-9_])`)
+	}
+	return mm
+}
+
+// match reports whether msg forwards from, links to or mentions the target.
+func (mm mentionMatcher) match(msg *tg.Message) (forwarded, linked, mentioned bool) {
+	if from, ok := msg.FwdFrom.GetFromID(); ok {
+		if ch, ok := from.(*tg.PeerChannel); ok && ch.ChannelID == mm.channelID {
+			forwarded = true
+		}
+	}
+	for _, entity := range msg.Entities {
+		switch e := entity.(type) {
+		case *tg.MessageEntityMention:
+			if mm.mention != "" && strings.ToLower(entityText(msg.Message, e.Offset, e.Length)) == mm.mention {
+				mentioned = true
+			}
+		case *tg.MessageEntityURL:
+			if mm.link != nil && mm.link.MatchString(entityText(msg.Message, e.Offset, e.Length)) {
+				linked = true
+			}
+		case *tg.MessageEntityTextURL:
+			if mm.link != nil && mm.link.MatchString(e.URL) {
+				linked = true
+			}
+		}
+	}
+	return forwarded, linked, mentioned
+}
+
+func (r *Referrer) update(msg *tg.Message, mm mentionMatcher) {
+	forwarded, linked, mentioned := mm.match(msg)
+	if !forwarded && !linked && !mentioned {
+		return
+	}
+	if forwarded {
+		r.Forwards += 1
+	}
+	if linked {
+		r.Links += 1
+	}
+	if mentioned {
+		r.Mentions += 1
+	}
+	r.References += 1
+	r.Reach += msg.Views
+	if at := getDateTime(msg.Date); at.After(r.LastReferenceAt) {
+		r.LastReferenceAt = at
+	}
+}
+
+// scanReferrer returns the batch handler that looks for references in the
+// history of one watched chat.
+func scanReferrer(r *Referrer, mm mentionMatcher, window Window) batchHandler {
+	minDateUnix := window.startUnix()
+	maxDateUnix := window.endUnix()
+	return func(m tg.ModifiedMessagesMessages) int {
+		offSet := 0
+		for _, msg := range m.GetMessages() {
+			notEmpty, ok := msg.AsNotEmpty()
+			if !ok {
+				continue
+			}
+			offSet = notEmpty.GetDate()
+			if offSet <= minDateUnix {
+				return minDateUnix
+			}
+			if offSet >= maxDateUnix {
+				continue
+			}
+			if post, ok := msg.(*tg.Message); ok {
+				r.update(post, mm)
+			}
+		}
+		return offSet
+	}
+}
+
+// DiscoverMentions crawls the history of every watched chat inside window,
+// through the same crawler and rate limiter as ProcessAnalytics, and reports
+// the ones that forwarded from, linked to or mentioned the target channel.
+func (ar *Analyzer) DiscoverMentions(username string, watchlist []string, window Window) (*MentionReport, error) {
+	log := logger.With("operation", "DiscoverMentions", "username", username, "window", window.Key())
+	log.Info("Starting mention discovery", "watchlist", len(watchlist))
+
+	startTime := time.Now()
+	report := &MentionReport{
+		Target:    username,
+		Window:    window,
+		Referrers: make([]Referrer, 0),
+		Errors:    make(map[string]string),
+	}
+
+	if err := ar.run(context.Background(), func(ctx context.Context) error {
+		target, err := ar.GetChannel(ctx, username)
+		if err != nil {
+			return err
+		}
+		matcher := newMentionMatcher(target)
+		api := ar.client.API()
+
+		for _, name := range watchlist {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if strings.EqualFold(strings.TrimPrefix(name, "@"), target.Username) {
+				continue
+			}
+
+			chat, err := ar.ResolveChat(ctx, name)
+			if err != nil {
+				log.Warn("Failed to resolve watched chat, skipping", "watched", name, "error", err)
+				report.Errors[name] = err.Error()
+				continue
+			}
+
+			referrer := Referrer{ID: chat.GetID()}
+			var peer tg.InputPeerClass
+			switch c := chat.(type) {
+			case *tg.Channel:
+				peer = c.AsInputPeer()
+				referrer.Title, referrer.Username = c.Title, c.Username
+			case *tg.Chat:
+				peer = &tg.InputPeerChat{ChatID: c.ID}
+				referrer.Title = c.Title
+			default:
+				report.Errors[name] = "not a channel or group"
+				continue
+			}
+
+			chatLog := log.With("watched", name)
+			scanned, err := ar.crawlHistory(ctx, api, peer, referrer.Title, window, chatLog, scanReferrer(&referrer, matcher, window))
+			report.MessagesScanned += scanned
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				chatLog.Warn("Failed to crawl watched chat, skipping", "error", err)
+				report.Errors[name] = err.Error()
+				continue
+			}
+			report.ChannelsScanned += 1
+			if referrer.References != 0 {
+				report.Referrers = append(report.Referrers, referrer)
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Error("Mention discovery failed", "error", err, "duration", time.Since(startTime))
+		return nil, err
+	}
+
+	sort.Slice(report.Referrers, func(i, j int) bool {
+		if report.Referrers[i].References == report.Referrers[j].References {
+			return report.Referrers[i].Reach > report.Referrers[j].Reach
+		}
+		return report.Referrers[i].References > report.Referrers[j].References
+	})
+
+	log.Info("Mention discovery complete",
+		"duration", time.Since(startTime),
+		"channels_scanned", report.ChannelsScanned,
+		"referrers", len(report.Referrers))
+	return report, nil
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestMentionMatcher(t *testing.T) {
+	mm := newMentionMatcher(&tg.Channel{ID: 42, Username: "GoNews"})
+
+	text := "Follow @gonews and https://t.me/GoNews/120 but not t.me/gonewsletter"
+	msg := &tg.Message{
+		Message: text,
+		Entities: []tg.MessageEntityClass{
+			&tg.MessageEntityMention{Offset: 7, Length: 7},
+			&tg.MessageEntityURL{Offset: 19, Length: 23},
+		},
+	}
+	if forwarded, linked, mentioned := mm.match(msg); forwarded || !linked || !mentioned {
+		t.Fatalf("match = %v %v %v, want link and mention", forwarded, linked, mentioned)
+	}
+
+	other := &tg.Message{
+		Message:  "t.me/gonewsletter",
+		Entities: []tg.MessageEntityClass{&tg.MessageEntityURL{Offset: 0, Length: 17}},
+	}
+	if _, linked, _ := mm.match(other); linked {
+		t.Fatalf("a link to another channel sharing the prefix should not match")
+	}
+
+	forward := &tg.Message{}
+	fwd := tg.MessageFwdHeader{}
+	fwd.SetFromID(&tg.PeerChannel{ChannelID: 42})
+	forward.SetFwdFrom(fwd)
+	r := Referrer{}
+	r.update(forward, mm)
+	if r.Forwards != 1 || r.References != 1 {
+		t.Fatalf("referrer = %+v, want one forward", r)
+	}
+}
+
+func TestMentionMatcherPrivateChannel(t *testing.T) {
+	mm := newMentionMatcher(&tg.Channel{ID: 42})
+
+	text := "Ask @ and read https://t.me/anything"
+	msg := &tg.Message{
+		Message: text,
+		Entities: []tg.MessageEntityClass{
+			&tg.MessageEntityMention{Offset: 4, Length: 1},
+			&tg.MessageEntityURL{Offset: 15, Length: 21},
+			&tg.MessageEntityTextURL{Offset: 0, Length: 3, URL: "https://t.me/"},
+		},
+	}
+	if forwarded, linked, mentioned := mm.match(msg); forwarded || linked || mentioned {
+		t.Fatalf("match = %v %v %v, want nothing without a username", forwarded, linked, mentioned)
+	}
+
+	forward := &tg.Message{}
+	fwd := tg.MessageFwdHeader{}
+	fwd.SetFromID(&tg.PeerChannel{ChannelID: 42})
+	forward.SetFwdFrom(fwd)
+	if forwarded, _, _ := mm.match(forward); !forwarded {
+		t.Fatalf("expected forwards of a private channel to match")
+	}
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"bytes"
+
+	"github.com/gotd/td/tg"
+)
+
+type PollSummary struct {
+	PostID        int    `json:"post_id"`
+	Question      string `json:"question"`
+	Quiz          bool   `json:"quiz"`
+	TotalVoters   int    `json:"total_voters"`
+	WinningOption string `json:"winning_option"`
+	WinningVotes  int    `json:"winning_votes"`
+}
+
+// PollStats covers the polls and quizzes posted in the window. Telegram only
+// reveals per option results, and which quiz answer is correct, once the
+// account has voted, so correctness is computed over the quizzes where it is
+// known.
+type PollStats struct {
+	Polls             int         `json:"polls"`
+	Quizzes           int         `json:"quizzes"`
+	TotalVoters       int         `json:"total_voters"`
+	AverageTurnout    float64     `json:"average_turnout"`
+	MostVoted         PollSummary `json:"most_voted"`
+	QuizzesWithAnswer int         `json:"quizzes_with_answer"`
+	QuizCorrectRate   float64     `json:"quiz_correct_rate"`
+
+	turnouts      []float64
+	quizVoters    int
+	correctVoters int
+}
+
+func (p *PollStats) UpdatePolls(msg *tg.Message) {
+	media, ok := msg.Media.(*tg.MessageMediaPoll)
+	if !ok {
+		return
+	}
+	poll, results := media.Poll, media.Results
+
+	p.Polls += 1
+	p.TotalVoters += results.TotalVoters
+	if msg.Views != 0 {
+		p.turnouts = append(p.turnouts, ratio(results.TotalVoters, msg.Views))
+	}
+
+	if results.TotalVoters > p.MostVoted.TotalVoters || p.MostVoted.PostID == 0 {
+		p.MostVoted = PollSummary{
+			PostID:      msg.ID,
+			Question:    poll.Question.Text,
+			Quiz:        poll.Quiz,
+			TotalVoters: results.TotalVoters,
+		}
+		if winner, ok := winningOption(results.Results); ok {
+			p.MostVoted.WinningOption = optionText(poll.Answers, winner.Option)
+			p.MostVoted.WinningVotes = winner.Voters
+		}
+	}
+
+	if !poll.Quiz {
+		return
+	}
+	p.Quizzes += 1
+	for _, answer := range results.Results {
+		if !answer.Correct {
+			continue
+		}
+		p.QuizzesWithAnswer += 1
+		p.quizVoters += results.TotalVoters
+		p.correctVoters += answer.Voters
+		break
+	}
+}
+
+// GetPollStats derives the averages once the crawl is complete.
+func (p *PollStats) GetPollStats() {
+	p.AverageTurnout = mean(p.turnouts)
+	p.QuizCorrectRate = ratio(p.correctVoters, p.quizVoters)
+}
+
+func winningOption(results []tg.PollAnswerVoters) (tg.PollAnswerVoters, bool) {
+	var winner tg.PollAnswerVoters
+	found := false
+	for _, answer := range results {
+		if !found || answer.Voters > winner.Voters {
+			winner, found = answer, true
+		}
+	}
+	return winner, found
+}
+
+func optionText(answers []tg.PollAnswer, option []byte) string {
+	for _, answer := range answers {
+		if bytes.Equal(answer.Option, option) {
+			return answer.Text.Text
+		}
+	}
+	return ""
+}
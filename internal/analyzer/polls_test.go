@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/gotd/td/tg"
+)
+
+func pollMessage(id, views int, quiz bool, question string, votes map[string]int, correct string) *tg.Message {
+	poll := tg.Poll{Question: tg.TextWithEntities{Text: question}, Quiz: quiz}
+	results := tg.PollResults{}
+	for _, option := range []string{"a", "b", "c"} {
+		poll.Answers = append(poll.Answers, tg.PollAnswer{
+			Text:   tg.TextWithEntities{Text: "option " + option},
+			Option: []byte(option),
+		})
+		results.TotalVoters += votes[option]
+		// Results are only known once the account has voted
+		if votes != nil {
+			results.Results = append(results.Results, tg.PollAnswerVoters{
+				Option:  []byte(option),
+				Voters:  votes[option],
+				Correct: option == correct,
+			})
+		}
+	}
+	return &tg.Message{ID: id, Views: views, Media: &tg.MessageMediaPoll{Poll: poll, Results: results}}
+}
+
+func TestPollStats(t *testing.T) {
+	var p PollStats
+	messages := []*tg.Message{
+		pollMessage(1, 200, false, "Lunch?", map[string]int{"a": 10, "b": 40}, ""),
+		pollMessage(2, 100, true, "Capital?", map[string]int{"a": 30, "b": 10}, "a"),
+		pollMessage(3, 0, true, "Year?", map[string]int{"b": 5, "c": 15}, "b"),
+		// A quiz without known results still counts as a quiz
+		pollMessage(4, 100, true, "Unanswered?", nil, ""),
+		{ID: 5, Views: 1000, Message: "not a poll"},
+	}
+	for _, msg := range messages {
+		p.UpdatePolls(msg)
+	}
+	p.GetPollStats()
+
+	if p.Polls != 4 || p.Quizzes != 3 || p.QuizzesWithAnswer != 2 {
+		t.Fatalf("polls = %d, quizzes = %d with %d answered, want 4, 3 and 2", p.Polls, p.Quizzes, p.QuizzesWithAnswer)
+	}
+	if p.TotalVoters != 110 {
+		t.Fatalf("total voters = %d, want 110", p.TotalVoters)
+	}
+	// Turnouts of 0.25, 0.4 and 0, the poll without views is left out
+	if !closeTo(p.AverageTurnout, 0.65/3) {
+		t.Fatalf("average turnout = %v, want %v", p.AverageTurnout, 0.65/3)
+	}
+	// 30 of 40 and 5 of 20 voters picked the correct answer
+	if !closeTo(p.QuizCorrectRate, 35.0/60.0) {
+		t.Fatalf("quiz correct rate = %v, want %v", p.QuizCorrectRate, 35.0/60.0)
+	}
+	if p.MostVoted.PostID != 1 || p.MostVoted.WinningOption != "option b" || p.MostVoted.WinningVotes != 40 {
+		t.Fatalf("most voted = %+v, want post 1 won by option b with 40 votes", p.MostVoted)
+	}
+}
+
+func TestPollStatsEmpty(t *testing.T) {
+	var p PollStats
+	p.UpdatePolls(pollMessage(1, 100, true, "Unanswered?", nil, ""))
+	p.GetPollStats()
+
+	if p.AverageTurnout != 0 || p.QuizCorrectRate != 0 || p.QuizzesWithAnswer != 0 {
+		t.Fatalf("turnout = %v, correct rate = %v over %d quizzes, want zeros", p.AverageTurnout, p.QuizCorrectRate, p.QuizzesWithAnswer)
+	}
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+const defaultRequestsPerSecond = 5
+
+var (
+	sharedLimiter     *rateLimiter
+	sharedLimiterOnce sync.Once
+)
+
+// rateLimiter spaces Telegram API calls evenly. A single limiter is shared by
+// every analyzer in the process, so concurrent crawls (compare, mentions)
+// split the same budget instead of each flooding the account.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(requestsPerSecond int) *rateLimiter {
+	return &rateLimiter{interval: time.Second / time.Duration(requestsPerSecond)}
+}
+
+// processLimiter returns the process wide limiter, configured once from
+// TELEGRAM_REQUESTS_PER_SECOND.
+func processLimiter() *rateLimiter {
+	sharedLimiterOnce.Do(func() {
+		rps := defaultRequestsPerSecond
+		if value := os.Getenv("TELEGRAM_REQUESTS_PER_SECOND"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				logger.Warn("Invalid TELEGRAM_REQUESTS_PER_SECOND, using default", "value", value, "default", rps)
+			} else {
+				rps = n
+			}
+		}
+		sharedLimiter = newRateLimiter(rps)
+	})
+	return sharedLimiter
+}
+
+// Wait blocks until the next request slot or until ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Middleware applies the limiter to every call made through a client.
+func (l *rateLimiter) Middleware() telegram.Middleware {
+	return telegram.MiddlewareFunc(func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			if err := l.Wait(ctx); err != nil {
+				return err
+			}
+			return next.Invoke(ctx, input, output)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+const (
+	customReactionLimit = 20
+	customEmojiMimeWebp = "image/webp"
+)
+
+// ResolveCustomReactions fills in the alt emoji, sticker set and thumbnail for
+// the most used custom emoji reactions. Resolution failures are non-fatal, the
+// reaction is still reported with its document ID and count.
+func (ar *Analyzer) ResolveCustomReactions(ctx context.Context, api *tg.Client, tp *TopPosts) {
+	log := logger.With("operation", "ResolveCustomReactions")
+
+	top := tp.GetTopCustomReactions(customReactionLimit)
+	tp.CustomReactions = top
+	if len(top) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(top))
+	for _, r := range top {
+		ids = append(ids, r.DocumentID)
+	}
+	docs, err := api.MessagesGetCustomEmojiDocuments(ctx, ids)
+	if err != nil {
+		log.Warn("Failed to fetch custom emoji documents", "error", err)
+		return
+	}
+
+	documents := make(map[int64]*tg.Document)
+	for _, d := range docs {
+		if doc, ok := d.(*tg.Document); ok {
+			documents[doc.ID] = doc
+		}
+	}
+
+	stickerSets := make(map[int64]tg.StickerSet)
+	for i := range top {
+		doc, ok := documents[top[i].DocumentID]
+		if !ok {
+			continue
+		}
+		for _, attr := range doc.Attributes {
+			emojiAttr, ok := attr.(*tg.DocumentAttributeCustomEmoji)
+			if !ok {
+				continue
+			}
+			top[i].Alt = emojiAttr.Alt
+			if set, ok := ar.resolveStickerSet(ctx, api, emojiAttr.Stickerset, stickerSets); ok {
+				top[i].StickerSet = set.Title
+				top[i].SetName = set.ShortName
+			}
+		}
+		thumbnail, err := ar.downloadCustomEmojiThumb(ctx, doc)
+		if err != nil {
+			log.Warn("Failed to store custom emoji thumbnail",
+				"document_id", doc.ID,
+				"error", err)
+			continue
+		}
+		top[i].Thumbnail = thumbnail
+	}
+}
+
+func (ar *Analyzer) resolveStickerSet(ctx context.Context, api *tg.Client, input tg.InputStickerSetClass, cache map[int64]tg.StickerSet) (tg.StickerSet, bool) {
+	setID, ok := input.(*tg.InputStickerSetID)
+	if !ok {
+		return tg.StickerSet{}, false
+	}
+	if set, ok := cache[setID.ID]; ok {
+		return set, true
+	}
+	res, err := api.MessagesGetStickerSet(ctx, &tg.MessagesGetStickerSetRequest{Stickerset: setID})
+	if err != nil {
+		logger.Warn("Failed to resolve sticker set", "set_id", setID.ID, "error", err)
+		return tg.StickerSet{}, false
+	}
+	stickerSet, ok := res.(*tg.MessagesStickerSet)
+	if !ok {
+		return tg.StickerSet{}, false
+	}
+	cache[setID.ID] = stickerSet.Set
+	return stickerSet.Set, true
+}
+
+// downloadCustomEmojiThumb uploads a static preview of the custom emoji to the
+// bucket. Animated emoji only have a preview when Telegram provides a thumb,
+// static webp emoji are small enough to be stored as they are.
+func (ar *Analyzer) downloadCustomEmojiThumb(ctx context.Context, doc *tg.Document) (string, error) {
+	log := logger.With("operation", "downloadCustomEmojiThumb", "document_id", doc.ID)
+
+	thumbSize := ""
+	for _, thumb := range doc.Thumbs {
+		switch size := thumb.(type) {
+		case *tg.PhotoSize:
+			thumbSize = size.Type
+		case *tg.PhotoSizeProgressive:
+			thumbSize = size.Type
+		}
+		if thumbSize != "" {
+			break
+		}
+	}
+	if thumbSize == "" && doc.MimeType != customEmojiMimeWebp {
+		return "", fmt.Errorf("no static thumbnail for %s", doc.MimeType)
+	}
+
+	location := &tg.InputDocumentFileLocation{
+		ID:            doc.ID,
+		AccessHash:    doc.AccessHash,
+		FileReference: doc.FileReference,
+		ThumbSize:     thumbSize,
+	}
+	buf, err := ar.downloadFile(ctx, location, log)
+	if err != nil {
+		return "", err
+	}
+
+	fileName, _, err := ar.uploadFile(fmt.Sprintf("emoji-%d", doc.ID), buf, log)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/profiles/%s", fileName), nil
+}
@@ -0,0 +1,241 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gotd/td/tg"
+)
+
+type LengthBucket struct {
+	Label            string  `json:"label"`
+	MinCharacters    int     `json:"min_characters"`
+	MaxCharacters    int     `json:"max_characters"`
+	Posts            int     `json:"posts"`
+	AverageViews     float64 `json:"average_views"`
+	AverageReactions float64 `json:"average_reactions"`
+
+	views     int
+	reactions int
+}
+
+// lengthBuckets are the histogram ranges in characters, the last one is open.
+var lengthBuckets = [][2]int{{1, 100}, {101, 280}, {281, 500}, {501, 1000}, {1001, 2000}, {2001, math.MaxInt}}
+
+// ReadabilityStats measures how hard the posts written in one script are to
+// read: the average sentence length in words (words / sentences) and word
+// length in letters (letters / words), the two inputs of the usual
+// readability formulas. Ge'ez letters are syllables, so Amharic words are
+// short in letters and the scripts are only comparable with themselves.
+type ReadabilityStats struct {
+	Posts            int     `json:"posts"`
+	Sentences        int     `json:"sentences"`
+	Words            int     `json:"words"`
+	WordsPerSentence float64 `json:"words_per_sentence"`
+	LettersPerWord   float64 `json:"letters_per_word"`
+
+	letters int
+}
+
+// TextStats describes the length and formatting of the posts with text and
+// whether longer posts get more views or reactions.
+type TextStats struct {
+	TextPosts                  int            `json:"text_posts"`
+	Characters                 Distribution   `json:"characters"`
+	Words                      Distribution   `json:"words"`
+	LengthHistogram            []LengthBucket `json:"length_histogram"`
+	LengthViewsCorrelation     float64        `json:"length_views_correlation"`
+	LengthReactionsCorrelation float64        `json:"length_reactions_correlation"`
+	PostsWithFormatting        int            `json:"posts_with_formatting"`
+	FormattingRatio            float64        `json:"formatting_ratio"`
+	FormattingByType           map[string]int `json:"formatting_by_type"`
+	// Readability is keyed by the script most letters of a post are
+	// written in: ethiopic, latin or other
+	Readability map[string]*ReadabilityStats `json:"readability"`
+
+	characters []float64
+	words      []float64
+	views      []float64
+	reactions  []float64
+}
+
+func NewTextStats() TextStats {
+	histogram := make([]LengthBucket, 0, len(lengthBuckets))
+	for _, bounds := range lengthBuckets {
+		bucket := LengthBucket{
+			Label:         fmt.Sprintf("%d-%d", bounds[0], bounds[1]),
+			MinCharacters: bounds[0],
+			MaxCharacters: bounds[1],
+		}
+		// The open bucket has no upper bound, 0 in JSON
+		if bounds[1] == math.MaxInt {
+			bucket.Label = fmt.Sprintf("%d+", bounds[0])
+			bucket.MaxCharacters = 0
+		}
+		histogram = append(histogram, bucket)
+	}
+	return TextStats{
+		LengthHistogram:  histogram,
+		FormattingByType: make(map[string]int),
+		Readability:      make(map[string]*ReadabilityStats),
+	}
+}
+
+// scriptOf returns the script most letters of text are written in, empty
+// when it has no letters.
+func scriptOf(text string) string {
+	ethiopic, latin, other := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Ethiopic, r):
+			ethiopic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.IsLetter(r):
+			other++
+		}
+	}
+	switch {
+	case ethiopic+latin+other == 0:
+		return ""
+	case ethiopic >= latin && ethiopic >= other:
+		return "ethiopic"
+	case latin >= other:
+		return "latin"
+	default:
+		return "other"
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// countReadability counts the sentences, words and letters of text. Words are
+// runs of letters and digits, a dot inside one ("3.5", "t.me") included, split
+// by anything else, the Ge'ez word separator ፡ too. Sentences end at . ! ? …,
+// their Ge'ez forms ። ፧ ፨ or a line break, as posts often skip the final stop.
+func countReadability(text string) (sentences, words, letters int) {
+	runes := []rune(text)
+	inWord, inSentence := false, false
+	for i, r := range runes {
+		switch {
+		case isWordRune(r):
+			if !inWord {
+				words++
+				inWord = true
+			}
+			if unicode.IsLetter(r) {
+				letters++
+			}
+			inSentence = true
+		case r == '.' && inWord && i+1 < len(runes) && isWordRune(runes[i+1]):
+		case strings.ContainsRune(".!?…\n።፧፨", r):
+			inWord = false
+			if inSentence {
+				sentences++
+				inSentence = false
+			}
+		default:
+			inWord = false
+		}
+	}
+	if inSentence {
+		sentences++
+	}
+	return sentences, words, letters
+}
+
+// formattingType names the entities that style text, links and mentions are
+// not formatting.
+func formattingType(entity tg.MessageEntityClass) string {
+	switch entity.(type) {
+	case *tg.MessageEntityBold:
+		return "bold"
+	case *tg.MessageEntityItalic:
+		return "italic"
+	case *tg.MessageEntityUnderline:
+		return "underline"
+	case *tg.MessageEntityStrike:
+		return "strikethrough"
+	case *tg.MessageEntityCode:
+		return "code"
+	case *tg.MessageEntityPre:
+		return "pre"
+	case *tg.MessageEntitySpoiler:
+		return "spoiler"
+	case *tg.MessageEntityBlockquote:
+		return "blockquote"
+	default:
+		return ""
+	}
+}
+
+func (t *TextStats) UpdateText(msg *tg.Message) {
+	if msg.Message == "" {
+		return
+	}
+	characters := utf8.RuneCountInString(msg.Message)
+	reactions := countNumOfReactions(msg.Reactions).total
+
+	t.TextPosts += 1
+	t.characters = append(t.characters, float64(characters))
+	t.words = append(t.words, float64(len(strings.Fields(msg.Message))))
+	t.views = append(t.views, float64(msg.Views))
+	t.reactions = append(t.reactions, float64(reactions))
+
+	for i := range t.LengthHistogram {
+		if characters >= lengthBuckets[i][0] && characters <= lengthBuckets[i][1] {
+			t.LengthHistogram[i].Posts += 1
+			t.LengthHistogram[i].views += msg.Views
+			t.LengthHistogram[i].reactions += reactions
+			break
+		}
+	}
+
+	if script := scriptOf(msg.Message); script != "" {
+		r, ok := t.Readability[script]
+		if !ok {
+			r = &ReadabilityStats{}
+			t.Readability[script] = r
+		}
+		sentences, words, letters := countReadability(msg.Message)
+		r.Posts += 1
+		r.Sentences += sentences
+		r.Words += words
+		r.letters += letters
+	}
+
+	formatted := false
+	for _, entity := range msg.Entities {
+		if kind := formattingType(entity); kind != "" {
+			t.FormattingByType[kind] += 1
+			formatted = true
+		}
+	}
+	if formatted {
+		t.PostsWithFormatting += 1
+	}
+}
+
+// GetTextStats derives the distributions and correlations once the crawl is
+// complete.
+func (t *TextStats) GetTextStats() {
+	t.Characters = newDistribution(t.characters)
+	t.Words = newDistribution(t.words)
+	t.LengthViewsCorrelation = pearson(t.characters, t.views)
+	t.LengthReactionsCorrelation = pearson(t.characters, t.reactions)
+	t.FormattingRatio = ratio(t.PostsWithFormatting, t.TextPosts)
+	for i := range t.LengthHistogram {
+		bucket := &t.LengthHistogram[i]
+		bucket.AverageViews = ratio(bucket.views, bucket.Posts)
+		bucket.AverageReactions = ratio(bucket.reactions, bucket.Posts)
+	}
+	for _, r := range t.Readability {
+		r.WordsPerSentence = ratio(r.Words, r.Sentences)
+		r.LettersPerWord = ratio(r.letters, r.Words)
+	}
+}
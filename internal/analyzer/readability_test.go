@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestTextStats(t *testing.T) {
+	text := NewTextStats()
+	posts := []*tg.Message{
+		{Message: "short", Views: 100},
+		{Message: strings.Repeat("word ", 40), Views: 200, Entities: []tg.MessageEntityClass{&tg.MessageEntityBold{}, &tg.MessageEntitySpoiler{}}},
+		{Message: strings.Repeat("longer text ", 250), Views: 400},
+		{Views: 1000},
+	}
+	for _, msg := range posts {
+		text.UpdateText(msg)
+	}
+	text.GetTextStats()
+
+	if text.TextPosts != 3 {
+		t.Fatalf("text posts = %d, want 3", text.TextPosts)
+	}
+	if text.LengthHistogram[0].Posts != 1 || text.LengthHistogram[1].Posts != 1 || text.LengthHistogram[5].Posts != 1 {
+		t.Fatalf("histogram = %+v, want one post in 1-100, 101-280 and 2001+", text.LengthHistogram)
+	}
+	if text.LengthHistogram[5].Label != "2001+" {
+		t.Fatalf("open bucket label = %q, want 2001+", text.LengthHistogram[5].Label)
+	}
+	if text.LengthViewsCorrelation <= 0.9 {
+		t.Fatalf("length views correlation = %v, want strongly positive", text.LengthViewsCorrelation)
+	}
+	if text.PostsWithFormatting != 1 || text.FormattingByType["spoiler"] != 1 {
+		t.Fatalf("formatting = %d posts, %v, want 1 post with a spoiler", text.PostsWithFormatting, text.FormattingByType)
+	}
+	if pearson([]float64{1, 1}, []float64{2, 3}) != 0 {
+		t.Fatalf("correlation with a constant series should be 0")
+	}
+}
+
+func TestCountReadability(t *testing.T) {
+	cases := []struct {
+		name                      string
+		text                      string
+		sentences, words, letters int
+	}{
+		{"latin", "One two three. Four five!", 2, 5, 19},
+		{"ge'ez", "ሰላም ነው። እንዴት ነህ፧", 2, 4, 11},
+		{"ge'ez word separator", "ሰላም፡ነው።", 1, 2, 5},
+		{"dots inside words", "Version 3.5 is on t.me now", 1, 6, 17},
+		{"line breaks end sentences", "First line\nsecond line\n\n", 2, 4, 19},
+		{"no words", "🔥 ... 🔥", 0, 0, 0},
+	}
+
+	for _, tc := range cases {
+		sentences, words, letters := countReadability(tc.text)
+		if sentences != tc.sentences || words != tc.words || letters != tc.letters {
+			t.Fatalf("%s: got %d sentences, %d words, %d letters, want %d, %d and %d",
+				tc.name, sentences, words, letters, tc.sentences, tc.words, tc.letters)
+		}
+	}
+}
+
+func TestReadabilityByScript(t *testing.T) {
+	text := NewTextStats()
+	for _, msg := range []*tg.Message{
+		{Message: "One two three. Four five!"},
+		{Message: "Six seven."},
+		{Message: "ሰላም ነው። እንዴት ነህ፧"},
+		{Message: "🔥"},
+	} {
+		text.UpdateText(msg)
+	}
+	text.GetTextStats()
+
+	latin, ethiopic := text.Readability["latin"], text.Readability["ethiopic"]
+	if latin == nil || ethiopic == nil || len(text.Readability) != 2 {
+		t.Fatalf("readability = %v, want latin and ethiopic only", text.Readability)
+	}
+	if latin.Posts != 2 || !closeTo(latin.WordsPerSentence, 7.0/3.0) || !closeTo(latin.LettersPerWord, 27.0/7.0) {
+		t.Fatalf("latin = %+v, want 2 posts, 7/3 words per sentence and 27/7 letters per word", latin)
+	}
+	if ethiopic.Posts != 1 || ethiopic.WordsPerSentence != 2 || ethiopic.LettersPerWord != 2.75 {
+		t.Fatalf("ethiopic = %+v, want 1 post, 2 words per sentence and 2.75 letters per word", ethiopic)
+	}
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/sentiment"
+)
+
+const extremePostLimit = 5
+
+type ScoredPost struct {
+	PostID int       `json:"post_id"`
+	Text   string    `json:"text"`
+	Score  float64   `json:"score"`
+	Views  int       `json:"views"`
+	Date   time.Time `json:"date"`
+}
+
+// PostSentiment scores the text of every post. Posts without text, such as
+// bare photos, are not scored.
+type PostSentiment struct {
+	ScoredPosts    int                   `json:"scored_posts"`
+	Distribution   SentimentDistribution `json:"distribution"`
+	AverageByMonth map[string]float64    `json:"average_by_month"`
+	MostPositive   []ScoredPost          `json:"most_positive"`
+	MostNegative   []ScoredPost          `json:"most_negative"`
+
+	scorer       sentiment.Scorer
+	location     *time.Location
+	totalScore   float64
+	scoreByMonth map[string]float64
+	postsByMonth map[string]int
+}
+
+func (p *PostSentiment) UpdateSentiment(msg *tg.Message) {
+	if p.scorer == nil || msg.Message == "" {
+		return
+	}
+	score := p.scorer.Score(msg.Message)
+	dateTime := getDateTime(msg.Date)
+	if p.location != nil {
+		dateTime = dateTime.In(p.location)
+	}
+	month := MonthKey(dateTime)
+
+	p.ScoredPosts += 1
+	p.totalScore += score
+	p.Distribution.Add(score)
+	p.scoreByMonth[month] += score
+	p.postsByMonth[month] += 1
+
+	post := ScoredPost{PostID: msg.ID, Text: msg.Message, Score: score, Views: msg.Views, Date: dateTime}
+	if score > 0 {
+		p.MostPositive = insertScoredPost(p.MostPositive, post, func(a, b ScoredPost) bool { return a.Score > b.Score })
+	}
+	if score < 0 {
+		p.MostNegative = insertScoredPost(p.MostNegative, post, func(a, b ScoredPost) bool { return a.Score < b.Score })
+	}
+}
+
+// insertScoredPost keeps posts ordered by better and at most extremePostLimit
+// long, ties going to the more viewed post.
+func insertScoredPost(posts []ScoredPost, post ScoredPost, better func(a, b ScoredPost) bool) []ScoredPost {
+	posts = append(posts, post)
+	sort.SliceStable(posts, func(i, j int) bool {
+		if posts[i].Score == posts[j].Score {
+			return posts[i].Views > posts[j].Views
+		}
+		return better(posts[i], posts[j])
+	})
+	if len(posts) > extremePostLimit {
+		posts = posts[:extremePostLimit]
+	}
+	return posts
+}
+
+// GetSentimentStats derives the averages once the crawl is complete.
+func (p *PostSentiment) GetSentimentStats() {
+	if p.ScoredPosts != 0 {
+		p.Distribution.AverageScore = p.totalScore / float64(p.ScoredPosts)
+	}
+	for month, total := range p.scoreByMonth {
+		p.AverageByMonth[month] = total / float64(p.postsByMonth[month])
+	}
+	if p.MostPositive == nil {
+		p.MostPositive = make([]ScoredPost, 0)
+	}
+	if p.MostNegative == nil {
+		p.MostNegative = make([]ScoredPost, 0)
+	}
+}
@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+// telegramClient is the part of *telegram.Client the analyzer uses.
+type telegramClient interface {
+	Run(ctx context.Context, f func(ctx context.Context) error) error
+	API() *tg.Client
+	Auth() *auth.Client
+}
+
+var (
+	sessions   = make(map[string]*session)
+	sessionsMu sync.Mutex
+)
+
+// session keeps a single connected client per session file. Every analyzer of
+// the process (compare workers, the samplers, the tracker and webhook jobs)
+// sends its calls through that connection concurrently, the process limiter
+// spacing them, instead of each opening the file that a connected client
+// rewrites as its auth key and server salts change.
+type session struct {
+	client telegramClient
+
+	mu    sync.Mutex
+	ready chan struct{}
+	done  chan struct{}
+	err   error
+
+	// authMu keeps concurrent crawls from starting two sign in flows
+	authMu sync.Mutex
+}
+
+// processSession returns the session of path, creating its client with
+// newClient the first time the path is used.
+func processSession(path string, newClient func() telegramClient) *session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	s, ok := sessions[path]
+	if !ok {
+		s = &session{client: newClient()}
+		sessions[path] = s
+	}
+	return s
+}
+
+func newTelegramClient(appID int, appHash, path string) telegramClient {
+	return telegram.NewClient(appID, appHash, telegram.Options{
+		SessionStorage: &telegram.FileSessionStorage{Path: path},
+		Middlewares:    []telegram.Middleware{processLimiter().Middleware()},
+	})
+}
+
+// connect starts the connection unless it is already up or starting, and
+// returns the channels closed once it is ready and once it has ended.
+func (s *session) connect() (ready, done chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		select {
+		case <-s.done:
+		default:
+			return s.ready, s.done
+		}
+	}
+
+	ready, done = make(chan struct{}), make(chan struct{})
+	s.ready, s.done = ready, done
+	go func() {
+		// The connection outlives the calls, it only ends when it is lost
+		err := s.client.Run(context.Background(), func(ctx context.Context) error {
+			close(ready)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if err != nil {
+			logger.Warn("Telegram connection closed", "error", err)
+		}
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(done)
+	}()
+	return ready, done
+}
+
+// run calls f once the shared connection is up, reconnecting when it was
+// lost, or returns early when ctx is done while waiting.
+func (ar *Analyzer) run(ctx context.Context, f func(ctx context.Context) error) error {
+	ready, done := ar.session.connect()
+	select {
+	case <-ready:
+	case <-done:
+		ar.session.mu.Lock()
+		err := ar.session.err
+		ar.session.mu.Unlock()
+		return apperrors.NewAnalyzerError("connect", "", fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return f(ctx)
+}
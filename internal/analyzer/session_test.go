@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+)
+
+// fakeClient connects instantly and answers through invoker.
+type fakeClient struct {
+	invoker tg.Invoker
+	runs    int
+	mu      sync.Mutex
+}
+
+func (f *fakeClient) Run(ctx context.Context, run func(ctx context.Context) error) error {
+	f.mu.Lock()
+	f.runs++
+	f.mu.Unlock()
+	return run(ctx)
+}
+
+func (f *fakeClient) API() *tg.Client { return tg.NewClient(f.invoker) }
+
+func (f *fakeClient) Auth() *auth.Client { return nil }
+
+// barrierInvoker holds every call until want of them are in flight, then
+// answers each with an empty page.
+type barrierInvoker struct {
+	want int
+
+	mu       sync.Mutex
+	inFlight int
+	all      chan struct{}
+}
+
+func (b *barrierInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	b.mu.Lock()
+	b.inFlight++
+	if b.inFlight == b.want {
+		close(b.all)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.all:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var buf bin.Buffer
+	if err := (&tg.MessagesChannelMessages{}).Encode(&buf); err != nil {
+		return err
+	}
+	return output.Decode(&buf)
+}
+
+func TestSessionCrawlsInParallel(t *testing.T) {
+	invoker := &barrierInvoker{want: 2, all: make(chan struct{})}
+	client := &fakeClient{invoker: invoker}
+	ar := &Analyzer{client: client, session: &session{client: client}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Each crawl blocks until the other one is in flight too, so they only
+	// finish when the session lets both through at once
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errs := make(chan error, 2)
+	for _, name := range []string{"first", "second"} {
+		go func() {
+			errs <- ar.run(ctx, func(ctx context.Context) error {
+				_, err := ar.crawlHistory(ctx, ar.client.API(), &tg.InputPeerEmpty{}, name, YearWindow(2024), log,
+					func(m tg.ModifiedMessagesMessages) int { return 0 })
+				return err
+			})
+		}()
+	}
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Fatalf("crawl failed, the channels were not fetched in parallel: %v", err)
+		}
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.runs != 1 {
+		t.Fatalf("client connected %d times, want one shared connection", client.runs)
+	}
+}
@@ -0,0 +1,281 @@
+package analyzer
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/sentiment"
+)
+
+const (
+	threadCrawlLimit  = 10
+	threadPageLimit   = 5
+	threadCrawlDelay  = 500 * time.Millisecond
+	topCommenterLimit = 10
+	topThreadLimit    = 5
+)
+
+type threadCandidate struct {
+	postID   int
+	date     int
+	comments int
+}
+
+type CommenterStats struct {
+	UserID   int64  `json:"user_id,string"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Comments int    `json:"comments"`
+}
+
+type ThreadStats struct {
+	PostID                   int     `json:"post_id"`
+	Comments                 int     `json:"comments"`
+	CommentsAnalyzed         int     `json:"comments_analyzed"`
+	Participants             int     `json:"participants"`
+	FirstCommentAfterMinutes float64 `json:"first_comment_after_minutes"`
+}
+
+type SentimentDistribution struct {
+	Positive     int     `json:"positive"`
+	Neutral      int     `json:"neutral"`
+	Negative     int     `json:"negative"`
+	AverageScore float64 `json:"average_score"`
+}
+
+func (s *SentimentDistribution) Add(score float64) {
+	switch sentiment.Classify(score) {
+	case sentiment.Positive:
+		s.Positive += 1
+	case sentiment.Negative:
+		s.Negative += 1
+	default:
+		s.Neutral += 1
+	}
+}
+
+// DiscussionStats covers the comment threads of the channel's linked
+// discussion group. Only the most commented posts are crawled, see
+// threadCrawlLimit and threadPageLimit.
+type DiscussionStats struct {
+	ThreadsCrawled          int                   `json:"threads_crawled"`
+	CommentsAnalyzed        int                   `json:"comments_analyzed"`
+	TopCommenters           []CommenterStats      `json:"top_commenters"`
+	AverageCommentLength    float64               `json:"average_comment_length"`
+	FirstCommentDelay       Distribution          `json:"first_comment_delay_minutes"`
+	MostParticipatedThreads []ThreadStats         `json:"most_participated_threads"`
+	Sentiment               SentimentDistribution `json:"sentiment"`
+
+	candidates      []threadCandidate
+	commentsByUser  map[int64]int
+	commenters      map[int64]groupMember
+	commentChars    int
+	delays          []float64
+	threads         []ThreadStats
+	sentimentScores float64
+}
+
+func NewDiscussionStats() *DiscussionStats {
+	return &DiscussionStats{
+		commentsByUser: make(map[int64]int),
+		commenters:     make(map[int64]groupMember),
+	}
+}
+
+func (d *DiscussionStats) UpdateCandidates(msg *tg.Message) {
+	if !msg.Replies.Comments || msg.Replies.Replies == 0 {
+		return
+	}
+	d.candidates = append(d.candidates, threadCandidate{
+		postID:   msg.ID,
+		date:     msg.Date,
+		comments: msg.Replies.Replies,
+	})
+}
+
+// topCandidates returns the most commented posts, which are the threads worth
+// crawling.
+func (d *DiscussionStats) topCandidates() []threadCandidate {
+	top := make([]threadCandidate, len(d.candidates))
+	copy(top, d.candidates)
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].comments == top[j].comments {
+			return top[i].postID > top[j].postID
+		}
+		return top[i].comments > top[j].comments
+	})
+	if len(top) > threadCrawlLimit {
+		top = top[:threadCrawlLimit]
+	}
+	return top
+}
+
+func (d *DiscussionStats) updateCommenters(users []tg.UserClass) {
+	for _, u := range users {
+		user, ok := u.(*tg.User)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		d.commenters[user.ID] = groupMember{name: name, username: user.Username}
+	}
+}
+
+func (d *DiscussionStats) updateComment(comment *tg.Message, scorer sentiment.Scorer) int64 {
+	d.CommentsAnalyzed += 1
+	d.commentChars += utf8.RuneCountInString(comment.Message)
+	if comment.Message != "" {
+		score := scorer.Score(comment.Message)
+		d.Sentiment.Add(score)
+		d.sentimentScores += score
+	}
+	from, ok := comment.FromID.(*tg.PeerUser)
+	if !ok {
+		return 0
+	}
+	d.commentsByUser[from.UserID] += 1
+	return from.UserID
+}
+
+// GetDiscussionStats derives the averages and rankings once every thread has
+// been crawled.
+func (d *DiscussionStats) GetDiscussionStats() {
+	d.ThreadsCrawled = len(d.threads)
+	d.AverageCommentLength = ratio(d.commentChars, d.CommentsAnalyzed)
+	d.FirstCommentDelay = newDistribution(d.delays)
+	scored := d.Sentiment.Positive + d.Sentiment.Neutral + d.Sentiment.Negative
+	if scored != 0 {
+		d.Sentiment.AverageScore = d.sentimentScores / float64(scored)
+	}
+
+	commenters := make([]CommenterStats, 0, len(d.commentsByUser))
+	for userID, cnt := range d.commentsByUser {
+		member := d.commenters[userID]
+		commenters = append(commenters, CommenterStats{
+			UserID:   userID,
+			Name:     member.name,
+			Username: member.username,
+			Comments: cnt,
+		})
+	}
+	sort.Slice(commenters, func(i, j int) bool {
+		if commenters[i].Comments == commenters[j].Comments {
+			return commenters[i].UserID < commenters[j].UserID
+		}
+		return commenters[i].Comments > commenters[j].Comments
+	})
+	if len(commenters) > topCommenterLimit {
+		commenters = commenters[:topCommenterLimit]
+	}
+	d.TopCommenters = commenters
+
+	threads := make([]ThreadStats, len(d.threads))
+	copy(threads, d.threads)
+	sort.Slice(threads, func(i, j int) bool {
+		if threads[i].Participants == threads[j].Participants {
+			return threads[i].Comments > threads[j].Comments
+		}
+		return threads[i].Participants > threads[j].Participants
+	})
+	if len(threads) > topThreadLimit {
+		threads = threads[:topThreadLimit]
+	}
+	d.MostParticipatedThreads = threads
+}
+
+// CrawlDiscussions fetches the comments of the most commented posts through
+// messages.getReplies. Each thread is crawled for at most threadPageLimit pages
+// and requests are spaced by threadCrawlDelay to avoid flooding.
+func (ar *Analyzer) CrawlDiscussions(ctx context.Context, api *tg.Client, channel *tg.Channel, d *DiscussionStats) {
+	log := logger.With("operation", "CrawlDiscussions", "channel_id", channel.ID)
+
+	for _, candidate := range d.topCandidates() {
+		if ctx.Err() != nil {
+			log.Warn("Discussion crawl cancelled", "error", ctx.Err())
+			break
+		}
+		thread := ar.crawlThread(ctx, api, channel, candidate, d, log)
+		d.threads = append(d.threads, thread)
+	}
+
+	d.GetDiscussionStats()
+	log.Info("Discussion crawl complete",
+		"threads", d.ThreadsCrawled,
+		"comments", d.CommentsAnalyzed)
+}
+
+func (ar *Analyzer) crawlThread(ctx context.Context, api *tg.Client, channel *tg.Channel, candidate threadCandidate, d *DiscussionStats, log *slog.Logger) ThreadStats {
+	thread := ThreadStats{PostID: candidate.postID, Comments: candidate.comments}
+	participants := make(map[int64]bool)
+
+	offsetID := 0
+	for page := 0; page < threadPageLimit; page++ {
+		res, err := api.MessagesGetReplies(ctx, &tg.MessagesGetRepliesRequest{
+			Peer:     channel.AsInputPeer(),
+			MsgID:    candidate.postID,
+			OffsetID: offsetID,
+			Limit:    defaultMessageLimit,
+		})
+		if err != nil {
+			log.Warn("Failed to fetch thread page", "post_id", candidate.postID, "page", page, "error", err)
+			break
+		}
+		m, ok := res.AsModified()
+		if !ok || len(m.GetMessages()) == 0 {
+			break
+		}
+
+		d.updateCommenters(m.GetUsers())
+		for _, msg := range m.GetMessages() {
+			comment, ok := msg.(*tg.Message)
+			if !ok {
+				continue
+			}
+			thread.CommentsAnalyzed += 1
+			if userID := d.updateComment(comment, ar.scorer); userID != 0 {
+				participants[userID] = true
+			}
+			offsetID = comment.ID
+		}
+		if len(m.GetMessages()) < defaultMessageLimit {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			thread.Participants = len(participants)
+			return thread
+		case <-time.After(threadCrawlDelay):
+		}
+	}
+	thread.Participants = len(participants)
+
+	// The oldest reply is the one right after offset 1 in the thread
+	res, err := api.MessagesGetReplies(ctx, &tg.MessagesGetRepliesRequest{
+		Peer:      channel.AsInputPeer(),
+		MsgID:     candidate.postID,
+		OffsetID:  1,
+		AddOffset: -1,
+		Limit:     1,
+	})
+	if err != nil {
+		log.Warn("Failed to fetch first comment", "post_id", candidate.postID, "error", err)
+		return thread
+	}
+	if m, ok := res.AsModified(); ok && len(m.GetMessages()) != 0 {
+		if first, ok := m.GetMessages()[0].AsNotEmpty(); ok && first.GetDate() >= candidate.date {
+			thread.FirstCommentAfterMinutes = float64(first.GetDate()-candidate.date) / 60
+			d.delays = append(d.delays, thread.FirstCommentAfterMinutes)
+		}
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(threadCrawlDelay):
+	}
+	return thread
+}
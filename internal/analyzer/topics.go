@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/topics"
+)
+
+// maxTopicDocuments bounds the posts kept in memory for clustering. The crawl
+// runs newest first, so the most recent posts are the ones clustered.
+const maxTopicDocuments = 5000
+
+// TopicStats groups the posts with text into topics, see topics.Cluster.
+type TopicStats struct {
+	Topics         []topics.Topic `json:"topics"`
+	MostDiscussed  string         `json:"most_discussed"`
+	BestPerforming string         `json:"best_performing"`
+
+	documents []topics.Document
+}
+
+func (t *TopicStats) UpdateTopics(msg *tg.Message) {
+	if msg.Message == "" || len(t.documents) >= maxTopicDocuments {
+		return
+	}
+	t.documents = append(t.documents, topics.Document{
+		ID:         msg.ID,
+		Text:       msg.Message,
+		Views:      msg.Views,
+		Engagement: postEngagement(msg),
+	})
+}
+
+// GetTopics clusters the collected posts once the crawl is complete. The best
+// performing topic has the highest average engagement rate among the topics
+// with at least the average number of posts, so a single viral post does not
+// win.
+func (t *TopicStats) GetTopics() {
+	t.Topics = topics.Cluster(t.documents, 0)
+	t.documents = nil
+	if len(t.Topics) == 0 {
+		return
+	}
+	t.MostDiscussed = t.Topics[0].Label
+
+	posts := 0
+	for _, topic := range t.Topics {
+		posts += topic.Posts
+	}
+	best := -1.0
+	for _, topic := range t.Topics {
+		if topic.Posts*len(t.Topics) >= posts && topic.AverageEngagementRate > best {
+			t.BestPerforming, best = topic.Label, topic.AverageEngagementRate
+		}
+	}
+}
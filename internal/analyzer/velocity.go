@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gotd/td/tg"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/velocity"
+)
+
+// velocityGrace keeps a post tracked a little past the last checkpoint, so a
+// sample due right at the end is not lost to the sampling interval.
+const velocityGrace = time.Hour
+
+// ValidateChannel checks that username resolves to a broadcast channel, the
+// only kind of chat whose post views can be sampled.
+func (ar *Analyzer) ValidateChannel(ctx context.Context, username string) error {
+	return ar.run(ctx, func(ctx context.Context) error {
+		_, err := ar.GetChannel(ctx, username)
+		return err
+	})
+}
+
+// SampleViews records the views of the recent posts of every registered
+// channel that reached a checkpoint since the last run. New posts are found
+// on the latest history page, their views are then read in batches through
+// ChannelsGetMessages.
+func (ar *Analyzer) SampleViews(ctx context.Context, store *velocity.Store) error {
+	log := logger.With("operation", "SampleViews")
+
+	channels, err := store.Channels()
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		log.Debug("No channels registered for view sampling")
+		return nil
+	}
+
+	return ar.run(ctx, func(ctx context.Context) error {
+		for _, username := range channels {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			channelLog := log.With("username", username)
+			sampled, err := ar.sampleChannel(ctx, store, username, channelLog)
+			if err != nil {
+				channelLog.Warn("Failed to sample channel views, skipping", "error", err)
+				continue
+			}
+			channelLog.Info("Channel views sampled", "posts", sampled)
+		}
+		return nil
+	})
+}
+
+func (ar *Analyzer) sampleChannel(ctx context.Context, store *velocity.Store, username string, log *slog.Logger) (int, error) {
+	channel, err := ar.GetChannel(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	api := ar.client.API()
+	now := time.Now()
+	cutoff := now.Add(-velocity.TrackingPeriod - velocityGrace)
+
+	stored, err := store.Series(username)
+	if err != nil {
+		return 0, err
+	}
+	tracked := make(map[int]*velocity.PostSeries)
+	for i := range stored {
+		if stored[i].PublishedAt.After(cutoff) {
+			tracked[stored[i].PostID] = &stored[i]
+		}
+	}
+
+	res, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+		Peer:  channel.AsInputPeer(),
+		Limit: defaultMessageLimit,
+	})
+	if err != nil {
+		return 0, apperrors.NewAnalyzerError("sample_views", username, fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+	}
+	if m, ok := res.AsModified(); ok {
+		for _, message := range m.GetMessages() {
+			msg, ok := message.(*tg.Message)
+			if !ok {
+				continue
+			}
+			published := getDateTime(msg.Date)
+			if _, ok := tracked[msg.ID]; ok || !published.After(cutoff) {
+				continue
+			}
+			series := &velocity.PostSeries{PostID: msg.ID, PublishedAt: published}
+			// Saved right away so the post stays tracked once it leaves the
+			// latest page
+			if err := store.Save(username, *series); err != nil {
+				log.Warn("Failed to save new post series", "post_id", msg.ID, "error", err)
+			}
+			tracked[msg.ID] = series
+		}
+	}
+
+	due := make(map[int]velocity.Checkpoint)
+	ids := make([]tg.InputMessageClass, 0)
+	for id, series := range tracked {
+		if checkpoint, ok := series.Due(now); ok {
+			due[id] = checkpoint
+			ids = append(ids, &tg.InputMessageID{ID: id})
+		}
+	}
+
+	sampled := 0
+	for start := 0; start < len(ids); start += defaultMessageLimit {
+		end := min(start+defaultMessageLimit, len(ids))
+		res, err := api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+			Channel: channel.AsInput(),
+			ID:      ids[start:end],
+		})
+		if err != nil {
+			return sampled, apperrors.NewAnalyzerError("sample_views", username, fmt.Errorf("%w: %v", apperrors.ErrTelegramAPI, err))
+		}
+		m, ok := res.AsModified()
+		if !ok {
+			continue
+		}
+		for _, message := range m.GetMessages() {
+			// Deleted posts come back as empty messages and are no longer sampled
+			msg, ok := message.(*tg.Message)
+			if !ok {
+				continue
+			}
+			series, ok := tracked[msg.ID]
+			if !ok {
+				continue
+			}
+			series.Record(due[msg.ID], now, msg.Views)
+			if err := store.Save(username, *series); err != nil {
+				log.Warn("Failed to save views sample", "post_id", msg.ID, "error", err)
+				continue
+			}
+			sampled += 1
+		}
+	}
+	return sampled, nil
+}
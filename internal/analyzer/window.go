@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+)
+
+const windowDateLayout = "2006-01-02"
+
+var defaultWindowStart = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Window is the time range a crawl covers. Start is inclusive and End is
+// exclusive, a zero End means the window is open and runs until now.
+type Window struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Timezone string    `json:"timezone,omitempty"`
+}
+
+func DefaultWindow() Window {
+	return Window{Start: defaultWindowStart}
+}
+
+func YearWindow(year int) Window {
+	return Window{
+		Start: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// ParseWindow builds a window from either a calendar year or an inclusive
+// from/to date range in YYYY-MM-DD format. With neither set it returns the
+// default window.
+func ParseWindow(year int, from, to string) (Window, error) {
+	if year != 0 {
+		if from != "" || to != "" {
+			return Window{}, fmt.Errorf("%w: year cannot be combined with from/to", apperrors.ErrInvalidWindow)
+		}
+		return YearWindow(year), nil
+	}
+	if from == "" && to == "" {
+		return DefaultWindow(), nil
+	}
+
+	w := DefaultWindow()
+	if from != "" {
+		start, err := time.Parse(windowDateLayout, from)
+		if err != nil {
+			return Window{}, fmt.Errorf("%w: invalid from date: %v", apperrors.ErrInvalidWindow, err)
+		}
+		w.Start = start
+	}
+	if to != "" {
+		end, err := time.Parse(windowDateLayout, to)
+		if err != nil {
+			return Window{}, fmt.Errorf("%w: invalid to date: %v", apperrors.ErrInvalidWindow, err)
+		}
+		w.End = end.AddDate(0, 0, 1)
+	}
+	if !w.End.IsZero() && !w.End.After(w.Start) {
+		return Window{}, fmt.Errorf("%w: to must not be before from", apperrors.ErrInvalidWindow)
+	}
+	return w, nil
+}
+
+// In moves the window boundaries to midnight of the same dates in the named
+// IANA timezone, e.g. "Africa/Addis_Ababa". An empty name keeps UTC.
+func (w Window) In(timezone string) (Window, error) {
+	if timezone == "" {
+		return w, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Window{}, fmt.Errorf("%w: invalid timezone: %v", apperrors.ErrInvalidWindow, err)
+	}
+	w.Start = time.Date(w.Start.Year(), w.Start.Month(), w.Start.Day(), 0, 0, 0, 0, loc)
+	if !w.End.IsZero() {
+		w.End = time.Date(w.End.Year(), w.End.Month(), w.End.Day(), 0, 0, 0, 0, loc)
+	}
+	w.Timezone = loc.String()
+	return w, nil
+}
+
+// Location returns the timezone posts are bucketed in, UTC unless the window
+// was moved with In.
+func (w Window) Location() *time.Location {
+	if w.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (w Window) IsDefault() bool {
+	return w.Start.Equal(defaultWindowStart) && w.End.IsZero() && w.Timezone == ""
+}
+
+// Key identifies the window in cache and snapshot keys.
+func (w Window) Key() string {
+	end := "now"
+	if !w.End.IsZero() {
+		end = w.End.Format(windowDateLayout)
+	}
+	key := w.Start.Format(windowDateLayout) + "_" + end
+	if w.Timezone != "" {
+		key += "@" + w.Timezone
+	}
+	return key
+}
+
+func (w Window) startUnix() int {
+	return int(w.Start.Unix())
+}
+
+// endUnix is the exclusive upper bound of the window as a unix timestamp.
+func (w Window) endUnix() int {
+	if w.End.IsZero() {
+		return int(time.Now().Unix())
+	}
+	return int(w.End.Unix())
+}
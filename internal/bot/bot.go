@@ -0,0 +1,294 @@
+// Package bot serves wraps through a Telegram bot: users send a channel
+// username in a private chat and get the summary back as messages, followed
+// by the story cards as an album.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/telegram/message/unpack"
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/cards"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+const (
+	defaultWorkers    = 1
+	defaultQueueLimit = 100
+	// positionUpdates bounds how many waiting users are told their new
+	// position after every dequeue, further back they only get the first one
+	positionUpdates = 10
+)
+
+const helpText = "Send me a public channel username, e.g. @durov, and I will wrap it up: " +
+	"totals, highlights and style of its posts since the start of 2025. " +
+	"Add a year for a calendar year instead, e.g. @durov 2024."
+
+// LoadFunc returns the analytics of a channel window, from the cache when
+// possible.
+type LoadFunc func(username string, window analyzer.Window) (*analyzer.Analytics, error)
+
+// CardFunc returns a story card of the analytics of a channel window as a
+// PNG, see cards.Slides.
+type CardFunc func(username string, window analyzer.Window, a *analyzer.Analytics, slide string) ([]byte, error)
+
+type Bot struct {
+	client  *telegram.Client
+	token   string
+	workers int
+	queue   *queue
+	load    LoadFunc
+	card    CardFunc
+	sender  *message.Sender
+}
+
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logger.Warn("Invalid "+name+", using default", "value", value, "default", fallback)
+		return fallback
+	}
+	return n
+}
+
+// New configures the bot from the environment: BOT_TOKEN, the APP_ID and
+// APP_HASH of the analyzer, BOT_SESSION_STORAGE, BOT_WORKERS and
+// BOT_QUEUE_LIMIT. Analyses run on the analyzer account, the bot only chats.
+func New(load LoadFunc, card CardFunc) (*Bot, error) {
+	token := os.Getenv("BOT_TOKEN")
+	if token == "" {
+		return nil, apperrors.NewConfigError("BOT_TOKEN", apperrors.ErrInvalidConfig)
+	}
+
+	appHash := os.Getenv("APP_HASH")
+	if appHash == "" {
+		return nil, apperrors.NewConfigError("APP_HASH", apperrors.ErrInvalidConfig)
+	}
+	appID, err := strconv.Atoi(os.Getenv("APP_ID"))
+	if err != nil {
+		return nil, apperrors.NewConfigError("APP_ID", fmt.Errorf("invalid integer: %w", err))
+	}
+
+	sessionPath := os.Getenv("BOT_SESSION_STORAGE")
+	if sessionPath == "" {
+		sessionPath = "bot-session.json"
+		logger.Warn("BOT_SESSION_STORAGE not set, using default", "default", sessionPath)
+	}
+
+	b := &Bot{
+		token:   token,
+		workers: intFromEnv("BOT_WORKERS", defaultWorkers),
+		queue:   newQueue(intFromEnv("BOT_QUEUE_LIMIT", defaultQueueLimit)),
+		load:    load,
+		card:    card,
+	}
+
+	dispatcher := tg.NewUpdateDispatcher()
+	dispatcher.OnNewMessage(b.onNewMessage)
+	b.client = telegram.NewClient(appID, appHash, telegram.Options{
+		SessionStorage: &telegram.FileSessionStorage{Path: sessionPath},
+		UpdateHandler:  dispatcher,
+	})
+	b.sender = message.NewSender(b.client.API())
+	return b, nil
+}
+
+// Run logs the bot in and serves users until ctx is done.
+func (b *Bot) Run(ctx context.Context) error {
+	log := logger.With("operation", "RunBot")
+
+	return b.client.Run(ctx, func(ctx context.Context) error {
+		status, err := b.client.Auth().Status(ctx)
+		if err != nil {
+			return apperrors.NewAnalyzerError("bot_auth", "", fmt.Errorf("%w: %v", apperrors.ErrAuthFailed, err))
+		}
+		if !status.Authorized {
+			if _, err := b.client.Auth().Bot(ctx, b.token); err != nil {
+				return apperrors.NewAnalyzerError("bot_auth", "", fmt.Errorf("%w: %v", apperrors.ErrAuthFailed, err))
+			}
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < b.workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b.work(ctx, log.With("worker", i))
+			}()
+		}
+		log.Info("Bot started", "workers", b.workers)
+
+		<-ctx.Done()
+		wg.Wait()
+		return ctx.Err()
+	})
+}
+
+func (b *Bot) onNewMessage(ctx context.Context, e tg.Entities, u *tg.UpdateNewMessage) error {
+	msg, ok := u.Message.(*tg.Message)
+	if !ok || msg.Out {
+		return nil
+	}
+	// Only private chats, groups would see every member's requests
+	from, ok := msg.PeerID.(*tg.PeerUser)
+	if !ok {
+		return nil
+	}
+	log := logger.With("operation", "BotMessage", "user_id", from.UserID)
+	reply := b.sender.Reply(e, u)
+
+	text := strings.TrimSpace(msg.Message)
+	if text == "" || text == "/start" || text == "/help" {
+		_, err := reply.Text(ctx, helpText)
+		return err
+	}
+
+	username, window, err := parseRequest(text)
+	if err != nil {
+		log.Debug("Invalid wrap request", "text", text, "error", err)
+		_, err := reply.Text(ctx, "I could not read that. "+helpText)
+		return err
+	}
+
+	peer, err := b.sender.Answer(e, u).AsInputPeer(ctx)
+	if err != nil {
+		return err
+	}
+	r := &request{userID: from.UserID, peer: peer, username: username, window: window}
+
+	position, err := b.queue.push(r)
+	switch {
+	case errors.Is(err, ErrAlreadyQueued) && position == 0:
+		_, err = reply.Text(ctx, "Your previous wrap is being prepared, send the next one when it is done.")
+		return err
+	case errors.Is(err, ErrAlreadyQueued):
+		_, err = reply.Textf(ctx, "You already have a wrap waiting, it is #%d in the queue.", position)
+		return err
+	case errors.Is(err, ErrQueueFull):
+		log.Warn("Bot queue is full")
+		_, err = reply.Text(ctx, "I am busy with a lot of wraps right now, please try again in a few minutes.")
+		return err
+	}
+
+	log.Info("Wrap request queued", "username", username, "window", window.Key(), "position", position)
+	id, err := unpack.MessageID(reply.Text(ctx, positionText(username, position)))
+	if err != nil {
+		log.Warn("Failed to send queue position", "error", err)
+		return nil
+	}
+	r.setStatus(id)
+	return nil
+}
+
+func positionText(username string, position int) string {
+	if position == 1 {
+		return fmt.Sprintf("🕐 @%s is next in the queue.", username)
+	}
+	return fmt.Sprintf("🕐 @%s is #%d in the queue, I will update this message as it moves.", username, position)
+}
+
+func (b *Bot) work(ctx context.Context, log *slog.Logger) {
+	for {
+		r, waiting, err := b.queue.pop(ctx)
+		if err != nil {
+			return
+		}
+		for i, w := range waiting[:min(positionUpdates, len(waiting))] {
+			b.updateStatus(ctx, w, positionText(w.username, i+1), log)
+		}
+		b.process(ctx, r, log.With("user_id", r.userID, "username", r.username))
+		b.queue.done(r)
+	}
+}
+
+// updateStatus edits the status message of a request, or sends a new one
+// when the first could not be sent.
+func (b *Bot) updateStatus(ctx context.Context, r *request, text string, log *slog.Logger) {
+	var err error
+	if id := r.status(); id != 0 {
+		_, err = b.sender.To(r.peer).Edit(id).Text(ctx, text)
+	} else {
+		var id int
+		id, err = unpack.MessageID(b.sender.To(r.peer).Text(ctx, text))
+		r.setStatus(id)
+	}
+	// Editing to the same text fails harmlessly
+	if err != nil && !strings.Contains(err.Error(), "MESSAGE_NOT_MODIFIED") {
+		log.Warn("Failed to update request status", "user_id", r.userID, "error", err)
+	}
+}
+
+func (b *Bot) process(ctx context.Context, r *request, log *slog.Logger) {
+	b.updateStatus(ctx, r, fmt.Sprintf("⏳ Wrapping @%s, big channels can take a few minutes…", r.username), log)
+
+	a, err := b.load(r.username, r.window)
+	if err != nil {
+		log.Error("Failed to wrap channel", "error", err)
+		b.updateStatus(ctx, r, failureText(r.username, err), log)
+		return
+	}
+
+	for _, parts := range summaryMessages(a) {
+		if _, err := b.sender.To(r.peer).StyledText(ctx, parts...); err != nil {
+			log.Error("Failed to send summary", "error", err)
+			b.updateStatus(ctx, r, "Something went wrong while sending your wrap, please try again.", log)
+			return
+		}
+	}
+	// The summary is already out, missing cards do not fail the wrap
+	if err := b.sendCards(ctx, r, a, log); err != nil {
+		log.Warn("Failed to send story cards", "error", err)
+	}
+	b.updateStatus(ctx, r, fmt.Sprintf("✅ @%s is wrapped!", r.username), log)
+	log.Info("Wrap sent")
+}
+
+// sendCards uploads the story cards of the wrap and sends them as one album,
+// skipping the slides that could not be rendered.
+func (b *Bot) sendCards(ctx context.Context, r *request, a *analyzer.Analytics, log *slog.Logger) error {
+	to := b.sender.To(r.peer)
+	album := make([]message.MultiMediaOption, 0, len(cards.Slides))
+	for _, slide := range cards.Slides {
+		data, err := b.card(r.username, r.window, a, slide)
+		if err != nil {
+			log.Warn("Failed to render story card, skipping it", "slide", slide, "error", err)
+			continue
+		}
+		file, err := to.Upload(message.FromBytes(slide+".png", data)).AsInputFile(ctx)
+		if err != nil {
+			return err
+		}
+		album = append(album, message.UploadedPhoto(file))
+	}
+	if len(album) == 0 {
+		return errors.New("no story card could be rendered")
+	}
+	_, err := to.Album(ctx, album[0], album[1:]...)
+	return err
+}
+
+func failureText(username string, err error) string {
+	switch {
+	case errors.Is(err, apperrors.ErrChannelNotFound):
+		return fmt.Sprintf("❌ I could not find @%s, check the username.", username)
+	case errors.Is(err, apperrors.ErrNotAChannel):
+		return fmt.Sprintf("❌ @%s is not a channel, I can only wrap channels and groups.", username)
+	default:
+		return fmt.Sprintf("❌ Wrapping @%s failed, please try again later.", username)
+	}
+}
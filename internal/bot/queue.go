@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrQueueFull     = errors.New("queue is full")
+	ErrAlreadyQueued = errors.New("already queued")
+)
+
+// queue is a FIFO of wrap requests shared by the workers. A user has at most
+// one request waiting or running at a time.
+type queue struct {
+	mu      sync.Mutex
+	pending []*request
+	active  map[int64]bool
+	limit   int
+	wake    chan struct{}
+}
+
+func newQueue(limit int) *queue {
+	return &queue{
+		active: make(map[int64]bool),
+		limit:  limit,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// push appends r and returns its 1-based position. When the user already has
+// a request the position of that one is returned with ErrAlreadyQueued, 0
+// when it is running.
+func (q *queue) push(r *request) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active[r.userID] {
+		for i, p := range q.pending {
+			if p.userID == r.userID {
+				return i + 1, ErrAlreadyQueued
+			}
+		}
+		return 0, ErrAlreadyQueued
+	}
+	if len(q.pending) >= q.limit {
+		return 0, ErrQueueFull
+	}
+
+	q.pending = append(q.pending, r)
+	q.active[r.userID] = true
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return len(q.pending), nil
+}
+
+// pop blocks until a request is waiting or ctx is done. It returns the
+// request and the ones still waiting, in order, so their users can be told
+// their new position.
+func (q *queue) pop(ctx context.Context) (*request, []*request, error) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) != 0 {
+			r := q.pending[0]
+			q.pending = q.pending[1:]
+			waiting := append([]*request(nil), q.pending...)
+			// Another worker may be waiting for the remaining requests
+			if len(q.pending) != 0 {
+				select {
+				case q.wake <- struct{}{}:
+				default:
+				}
+			}
+			q.mu.Unlock()
+			return r, waiting, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-q.wake:
+		}
+	}
+}
+
+// done releases the user of a finished request, who can then queue again.
+func (q *queue) done(r *request) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.active, r.userID)
+}
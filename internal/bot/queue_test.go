@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueuePositions(t *testing.T) {
+	q := newQueue(2)
+
+	if pos, err := q.push(&request{userID: 1}); err != nil || pos != 1 {
+		t.Fatalf("expected position 1, got %d (%v)", pos, err)
+	}
+	if pos, err := q.push(&request{userID: 2}); err != nil || pos != 2 {
+		t.Fatalf("expected position 2, got %d (%v)", pos, err)
+	}
+	if _, err := q.push(&request{userID: 3}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if pos, err := q.push(&request{userID: 2}); !errors.Is(err, ErrAlreadyQueued) || pos != 2 {
+		t.Fatalf("expected user 2 already queued at 2, got %d (%v)", pos, err)
+	}
+
+	r, waiting, err := q.pop(context.Background())
+	if err != nil || r.userID != 1 {
+		t.Fatalf("expected user 1 first, got %+v (%v)", r, err)
+	}
+	if len(waiting) != 1 || waiting[0].userID != 2 {
+		t.Fatalf("expected user 2 still waiting, got %d requests", len(waiting))
+	}
+
+	// Running requests keep their user busy until done
+	if pos, err := q.push(&request{userID: 1}); !errors.Is(err, ErrAlreadyQueued) || pos != 0 {
+		t.Fatalf("expected user 1 running, got %d (%v)", pos, err)
+	}
+	q.done(r)
+	if pos, err := q.push(&request{userID: 1}); err != nil || pos != 2 {
+		t.Fatalf("expected user 1 requeued at 2, got %d (%v)", pos, err)
+	}
+}
+
+func TestQueuePopWaits(t *testing.T) {
+	q := newQueue(10)
+	popped := make(chan int64, 1)
+	go func() {
+		r, _, err := q.pop(context.Background())
+		if err == nil {
+			popped <- r.userID
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := q.push(&request{userID: 7}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	select {
+	case id := <-popped:
+		if id != 7 {
+			t.Fatalf("expected user 7, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("pop did not return after push")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := q.pop(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		text     string
+		username string
+		year     int
+	}{
+		{"durov", "durov", 0},
+		{"@durov", "durov", 0},
+		{"https://t.me/durov/", "durov", 0},
+		{"t.me/durov 2024", "durov", 2024},
+	}
+	for _, tt := range tests {
+		username, window, err := parseRequest(tt.text)
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", tt.text, err)
+		}
+		if username != tt.username {
+			t.Fatalf("%q: expected %q, got %q", tt.text, tt.username, username)
+		}
+		if tt.year != 0 && window.Start.Year() != tt.year {
+			t.Fatalf("%q: expected a %d window, got %v", tt.text, tt.year, window)
+		}
+	}
+
+	for _, text := range []string{"", "hi there you", "ab", "@1channel", "durov 1999", "durov last"} {
+		if _, _, err := parseRequest(text); err == nil {
+			t.Fatalf("%q: expected an error", text)
+		}
+	}
+}
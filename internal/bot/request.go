@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/tg"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+)
+
+var ErrInvalidUsername = errors.New("invalid channel username")
+
+// usernamePattern follows Telegram's rules for public usernames.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{3,31}$`)
+
+// request is one wrap asked for by a user in a private chat with the bot.
+type request struct {
+	userID   int64
+	peer     tg.InputPeerClass
+	username string
+	window   analyzer.Window
+
+	mu sync.Mutex
+	// statusID is the bot message showing the queue position, later edited
+	// with the progress
+	statusID int
+}
+
+func (r *request) status() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statusID
+}
+
+func (r *request) setStatus(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statusID = id
+}
+
+// parseRequest reads a channel from a message, as "channel", "@channel" or a
+// t.me link, optionally followed by a year, e.g. "@channel 2024".
+func parseRequest(text string) (string, analyzer.Window, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", analyzer.Window{}, ErrInvalidUsername
+	}
+
+	username := fields[0]
+	for _, prefix := range []string{"https://", "http://", "t.me/", "telegram.me/", "@"} {
+		username = strings.TrimPrefix(username, prefix)
+	}
+	username = strings.TrimSuffix(username, "/")
+	if !usernamePattern.MatchString(username) {
+		return "", analyzer.Window{}, ErrInvalidUsername
+	}
+
+	year := 0
+	if len(fields) == 2 {
+		y, err := strconv.Atoi(fields[1])
+		// Telegram channels exist since 2015
+		if err != nil || y < 2015 || y > time.Now().Year() {
+			return "", analyzer.Window{}, fmt.Errorf("%w: invalid year %q", apperrors.ErrInvalidWindow, fields[1])
+		}
+		year = y
+	}
+	window, err := analyzer.ParseWindow(year, "", "")
+	if err != nil {
+		return "", analyzer.Window{}, err
+	}
+	return username, window, nil
+}
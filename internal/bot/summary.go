@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+)
+
+const snippetLength = 120
+
+// languageNames names the languages langid detects, undetermined posts are
+// left out.
+var languageNames = map[string]string{
+	"am": "Amharic",
+	"en": "English",
+	"om": "Afaan Oromo",
+}
+
+// compact formats a count the way Telegram shows views, e.g. 12.3K.
+func compact(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%.0fK", float64(n)/1_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:snippetLength]) + "…"
+}
+
+func windowLabel(w analyzer.Window) string {
+	if w.End.IsZero() {
+		return "since " + w.Start.Format("Jan 2, 2006")
+	}
+	if w.Start.Month() == time.January && w.Start.Day() == 1 && w.End.Equal(w.Start.AddDate(1, 0, 0)) {
+		return fmt.Sprintf("in %d", w.Start.Year())
+	}
+	return fmt.Sprintf("from %s to %s", w.Start.Format("Jan 2, 2006"), w.End.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+}
+
+func line(label, value string) []styling.StyledTextOption {
+	return []styling.StyledTextOption{styling.Plain(label + " "), styling.Bold(value), styling.Plain("\n")}
+}
+
+// summaryMessages renders the wrap as a few short messages: the overview,
+// the highlights and the character of the channel.
+func summaryMessages(a *analyzer.Analytics) [][]styling.StyledTextOption {
+	overview := []styling.StyledTextOption{
+		styling.Bold(fmt.Sprintf("📊 %s Wrapped", a.ChannelName)),
+		styling.Plain("\n" + windowLabel(a.Window) + "\n\n"),
+	}
+	if a.Info.Subscribers != 0 {
+		overview = append(overview, line("👥 Subscribers:", compact(a.Info.Subscribers))...)
+	}
+	overview = append(overview, line("📝 Posts:", compact(a.Totals.TotalPosts))...)
+	overview = append(overview, line("👀 Views:", compact(a.Totals.TotalViews))...)
+	overview = append(overview, line("❤️ Reactions:", compact(a.Totals.TotalReactions))...)
+	overview = append(overview, line("💬 Comments:", compact(a.Totals.TotalComments))...)
+	overview = append(overview, line("🔁 Shares:", compact(a.Engagement.TotalShares))...)
+	overview = append(overview, line("📈 Engagement rate:", fmt.Sprintf("%.2f%%", a.Engagement.EngagementRate*100))...)
+	overview = append(overview, line("🔥 Longest streak:", fmt.Sprintf("%d days", a.Trends.LongestPostingStreak))...)
+
+	highlights := []styling.StyledTextOption{styling.Bold("✨ Highlights\n\n")}
+	if a.Highlights.MostViewedCount != 0 {
+		post := a.Highlights.MostViewed
+		highlights = append(highlights, line("Most viewed post:", compact(post.Views)+" views")...)
+		if text := snippet(post.Text); text != "" {
+			highlights = append(highlights, styling.Italic(text), styling.Plain("\n"))
+		}
+		highlights = append(highlights, styling.Plain("\n"))
+	}
+	if a.Trends.BestPerformingDay != "" {
+		highlights = append(highlights, line("Best time to post:", fmt.Sprintf("%s at %02d:00", a.Trends.BestPerformingDay, a.Trends.BestPerformingHour))...)
+	}
+	if a.Highlights.MostForwardedSource.Name != "" {
+		highlights = append(highlights, line("Most forwarded from:", a.Highlights.MostForwardedSource.Name)...)
+	}
+	if a.Topics.MostDiscussed != "" {
+		highlights = append(highlights, line("Most discussed topic:", a.Topics.MostDiscussed)...)
+	}
+
+	character := []styling.StyledTextOption{styling.Bold("🎨 Style\n\n")}
+	if len(a.Emojis.TopEmojis) != 0 {
+		emojis := make([]string, 0, 5)
+		for _, e := range a.Emojis.TopEmojis[:min(5, len(a.Emojis.TopEmojis))] {
+			emojis = append(emojis, e.Emoji)
+		}
+		character = append(character, line("Favorite emojis:", strings.Join(emojis, " "))...)
+	}
+	if len(a.Content.TopHashtags) != 0 {
+		tags := make([]string, 0, 3)
+		for _, h := range a.Content.TopHashtags[:min(3, len(a.Content.TopHashtags))] {
+			tags = append(tags, h.Hashtag)
+		}
+		character = append(character, line("Top hashtags:", strings.Join(tags, " "))...)
+	}
+	if kind := topKey(a.Content.PostsByType); kind != "" {
+		character = append(character, line("Most posted:", kind)...)
+	}
+	languages := make(map[string]int, len(a.Languages.PostsByLanguage))
+	for code, posts := range a.Languages.PostsByLanguage {
+		if name, ok := languageNames[code]; ok {
+			languages[name] = posts
+		}
+	}
+	if language := topKey(languages); language != "" {
+		character = append(character, line("Main language:", language)...)
+	}
+	if a.Sentiment.ScoredPosts != 0 {
+		d := a.Sentiment.Distribution
+		character = append(character, line("Mood:", fmt.Sprintf("%d positive, %d neutral, %d negative", d.Positive, d.Neutral, d.Negative))...)
+	}
+
+	return [][]styling.StyledTextOption{overview, highlights, character}
+}
+
+// topKey returns the key with the highest count, ties broken alphabetically.
+func topKey(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	best := ""
+	for _, k := range keys {
+		if best == "" || counts[k] > counts[best] {
+			best = k
+		}
+	}
+	return best
+}
@@ -0,0 +1,438 @@
+// Package cards renders a wrap as 1080x1920 PNG story cards, one slide per
+// highlight, sized to be shared as a story.
+package cards
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	Width  = 1080
+	Height = 1920
+
+	margin         = 96
+	profileSize    = 240
+	snippetLines   = 10
+	topReactions   = 5
+	reactionRowGap = 140
+)
+
+var ErrUnknownSlide = errors.New("unknown card slide")
+
+// Slides are the cards of a wrap in the order they are meant to be shown.
+var Slides = []string{"overview", "top-post", "best-hour", "streak", "reactions"}
+
+type palette struct {
+	top, bottom, accent color.RGBA
+}
+
+var palettes = map[string]palette{
+	"overview":  {color.RGBA{0x1f, 0x2a, 0x6b, 0xff}, color.RGBA{0x0b, 0x0f, 0x2e, 0xff}, color.RGBA{0x5e, 0xd1, 0xff, 0xff}},
+	"top-post":  {color.RGBA{0x6b, 0x1f, 0x4f, 0xff}, color.RGBA{0x2a, 0x0b, 0x22, 0xff}, color.RGBA{0xff, 0x8a, 0xc8, 0xff}},
+	"best-hour": {color.RGBA{0x1f, 0x5e, 0x4a, 0xff}, color.RGBA{0x08, 0x24, 0x1c, 0xff}, color.RGBA{0x7c, 0xf2, 0xb8, 0xff}},
+	"streak":    {color.RGBA{0x7a, 0x33, 0x12, 0xff}, color.RGBA{0x2e, 0x10, 0x05, 0xff}, color.RGBA{0xff, 0xb4, 0x5e, 0xff}},
+	"reactions": {color.RGBA{0x4b, 0x1f, 0x7a, 0xff}, color.RGBA{0x18, 0x08, 0x2e, 0xff}, color.RGBA{0xc9, 0x9b, 0xff, 0xff}},
+}
+
+var (
+	white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	muted = color.NRGBA{0xff, 0xff, 0xff, 0xb0}
+	faint = color.NRGBA{0xff, 0xff, 0xff, 0x40}
+)
+
+// Render draws one slide of the wrap as a PNG. profile is the channel photo
+// and may be nil, the initial of the channel is drawn instead.
+func Render(a *analyzer.Analytics, slide string, profile image.Image) ([]byte, error) {
+	p, ok := palettes[slide]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSlide, slide)
+	}
+	fs, err := loadFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCanvas(fs, p)
+	c.header(a, profile)
+	switch slide {
+	case "overview":
+		c.overview(a)
+	case "top-post":
+		c.topPost(a)
+	case "best-hour":
+		c.bestHour(a)
+	case "streak":
+		c.streak(a)
+	case "reactions":
+		c.reactions(a)
+	}
+	c.centered(1820, "#TGWrapped", 36, false, muted)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canvas draws text and shapes on a card. Like bufio.Writer it keeps the
+// first error and ignores the drawing calls after it.
+type canvas struct {
+	img     *image.RGBA
+	fonts   *fontSet
+	palette palette
+	faces   map[float64]font.Face
+	bold    map[float64]font.Face
+	err     error
+}
+
+func newCanvas(fs *fontSet, p palette) *canvas {
+	c := &canvas{
+		img:     image.NewRGBA(image.Rect(0, 0, Width, Height)),
+		fonts:   fs,
+		palette: p,
+		faces:   make(map[float64]font.Face),
+		bold:    make(map[float64]font.Face),
+	}
+	for y := 0; y < Height; y++ {
+		t := float64(y) / float64(Height-1)
+		row := color.RGBA{
+			R: lerp(p.top.R, p.bottom.R, t),
+			G: lerp(p.top.G, p.bottom.G, t),
+			B: lerp(p.top.B, p.bottom.B, t),
+			A: 0xff,
+		}
+		draw.Draw(c.img, image.Rect(0, y, Width, y+1), image.NewUniform(row), image.Point{}, draw.Src)
+	}
+	return c
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+}
+
+func (c *canvas) face(size float64, bold bool) font.Face {
+	faces := c.faces
+	if bold {
+		faces = c.bold
+	}
+	if face, ok := faces[size]; ok {
+		return face
+	}
+	face, err := c.fonts.face(size, bold)
+	if err != nil {
+		c.err = err
+		return nil
+	}
+	faces[size] = face
+	return face
+}
+
+// text draws s with its baseline at y starting at x.
+func (c *canvas) text(x, y int, s string, size float64, bold bool, col color.Color) {
+	face := c.face(size, bold)
+	if c.err != nil {
+		return
+	}
+	d := font.Drawer{Dst: c.img, Src: image.NewUniform(col), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+func (c *canvas) measure(s string, size float64, bold bool) int {
+	face := c.face(size, bold)
+	if c.err != nil {
+		return 0
+	}
+	return font.MeasureString(face, s).Ceil()
+}
+
+// centered draws s centered horizontally with its baseline at y.
+func (c *canvas) centered(y int, s string, size float64, bold bool, col color.Color) {
+	c.text((Width-c.measure(s, size, bold))/2, y, s, size, bold, col)
+}
+
+// paragraph draws s centered and wrapped to the card width from the
+// baseline y, at most maxLines lines, and returns the baseline of the line
+// after it.
+func (c *canvas) paragraph(y int, s string, size float64, maxLines int, col color.Color) int {
+	lines := c.wrap(s, size, false, Width-2*margin)
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] = strings.TrimRight(lines[maxLines-1], " .,") + "…"
+	}
+	lineHeight := int(size * 1.4)
+	for _, line := range lines {
+		c.centered(y, line, size, false, col)
+		y += lineHeight
+	}
+	return y
+}
+
+// wrap breaks s into lines no wider than width, on spaces where possible and
+// inside words longer than a line.
+func (c *canvas) wrap(s string, size float64, bold bool, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if c.measure(candidate, size, bold) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = ""
+		for _, r := range word {
+			if line != "" && c.measure(line+string(r), size, bold) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (c *canvas) rect(r image.Rectangle, col color.Color) {
+	draw.Draw(c.img, r, image.NewUniform(col), image.Point{}, draw.Over)
+}
+
+// header draws the channel photo, name and window on top of every slide.
+func (c *canvas) header(a *analyzer.Analytics, profile image.Image) {
+	bounds := image.Rect((Width-profileSize)/2, 140, (Width+profileSize)/2, 140+profileSize)
+	if profile != nil {
+		src := squareCrop(profile.Bounds())
+		draw.CatmullRom.Scale(c.img, bounds, profile, src, draw.Over, &draw.Options{DstMask: circle{bounds}})
+	} else {
+		draw.DrawMask(c.img, bounds, image.NewUniform(c.palette.accent), image.Point{}, circle{bounds}, bounds.Min, draw.Over)
+		initial := "#"
+		for _, r := range a.ChannelName {
+			initial = strings.ToUpper(string(r))
+			break
+		}
+		c.centered(bounds.Min.Y+profileSize/2+40, initial, 120, true, c.palette.bottom)
+	}
+
+	name := a.ChannelName
+	if c.measure(name, 56, true) > Width-2*margin {
+		lines := c.wrap(name, 56, true, Width-2*margin)
+		name = strings.TrimSpace(lines[0]) + "…"
+	}
+	c.centered(480, name, 56, true, white)
+	c.centered(544, windowLabel(a.Window), 36, false, muted)
+}
+
+func (c *canvas) title(s string) {
+	c.centered(720, s, 64, true, c.palette.accent)
+}
+
+func (c *canvas) overview(a *analyzer.Analytics) {
+	c.title("By the numbers")
+	stats := []struct {
+		value int
+		label string
+	}{
+		{a.Totals.TotalPosts, "posts"},
+		{a.Totals.TotalViews, "views"},
+		{a.Totals.TotalReactions, "reactions"},
+		{a.Totals.TotalComments, "comments"},
+	}
+	for i, s := range stats {
+		y := 900 + i*220
+		c.centered(y, compact(s.value), 104, true, white)
+		c.centered(y+60, s.label, 40, false, muted)
+	}
+}
+
+func (c *canvas) topPost(a *analyzer.Analytics) {
+	c.title("Most viewed post")
+	post := a.Highlights.MostViewed
+	if a.Highlights.MostViewedCount == 0 {
+		c.centered(1000, "No views yet", 56, false, muted)
+		return
+	}
+	c.centered(900, compact(a.Highlights.MostViewedCount)+" views", 104, true, white)
+	if !post.Date.IsZero() {
+		c.centered(970, post.Date.In(a.Window.Location()).Format("January 2, 2006"), 40, false, muted)
+	}
+	if text := strings.Join(strings.Fields(post.Text), " "); text != "" {
+		c.paragraph(1110, "“"+text+"”", 44, snippetLines, white)
+	}
+}
+
+func (c *canvas) bestHour(a *analyzer.Analytics) {
+	c.title("Best time to post")
+	hour := a.Trends.BestPerformingHour
+	if hour < 0 || a.Trends.BestPerformingDay == "" {
+		c.centered(1000, "Not enough posts yet", 56, false, muted)
+		return
+	}
+	c.centered(900, a.Trends.BestPerformingDay+"s", 104, true, white)
+	c.centered(1020, fmt.Sprintf("at %02d:00", hour), 104, true, white)
+
+	// Posts by hour, the best hour stands out
+	top, bottom := 1180, 1560
+	most := 1
+	for _, n := range a.Trends.PostsByHour {
+		most = max(most, n)
+	}
+	slot := (Width - 2*margin) / 24
+	for h := 0; h < 24; h++ {
+		x := margin + h*slot
+		height := max(4, (bottom-top)*a.Trends.PostsByHour[h]/most)
+		col := color.Color(faint)
+		if h == hour {
+			col = c.palette.accent
+		}
+		c.rect(image.Rect(x+4, bottom-height, x+slot-4, bottom), col)
+	}
+	for _, h := range []int{0, 6, 12, 18} {
+		label := fmt.Sprintf("%02d", h)
+		c.text(margin+h*slot+(slot-c.measure(label, 32, false))/2, bottom+50, label, 32, false, muted)
+	}
+	c.centered(1700, "posts by hour, the highlighted hour", 36, false, muted)
+	c.centered(1750, "got the most views on average", 36, false, muted)
+}
+
+func (c *canvas) streak(a *analyzer.Analytics) {
+	c.title("Longest posting streak")
+	c.centered(1100, fmt.Sprintf("%d", a.Trends.LongestPostingStreak), 280, true, white)
+	days := "days in a row"
+	if a.Trends.LongestPostingStreak == 1 {
+		days = "day in a row"
+	}
+	c.centered(1190, days, 48, false, muted)
+	if a.Trends.AverageGapHours > 0 {
+		c.centered(1400, "a new post every", 40, false, muted)
+		c.centered(1490, gap(a.Trends.AverageGapHours), 72, true, white)
+		c.centered(1550, "on average", 40, false, muted)
+	}
+}
+
+func gap(hours float64) string {
+	switch {
+	case hours < 1:
+		return fmt.Sprintf("%.0f minutes", hours*60)
+	case hours < 48:
+		return fmt.Sprintf("%.1f hours", hours)
+	default:
+		return fmt.Sprintf("%.1f days", hours/24)
+	}
+}
+
+func (c *canvas) reactions(a *analyzer.Analytics) {
+	c.title("Top reactions")
+	c.centered(900, compact(a.Totals.TotalReactions), 104, true, white)
+	c.centered(960, "reactions", 40, false, muted)
+
+	top := topCounts(a.Highlights.ReactionsByType, topReactions)
+	if len(top) == 0 {
+		c.centered(1200, "No reactions yet", 56, false, muted)
+		return
+	}
+	barStart, barEnd := margin+150, Width-margin-180
+	for i, r := range top {
+		y := 1130 + i*reactionRowGap
+		c.text(margin, y+28, r.key, 80, false, white)
+		width := max(8, (barEnd-barStart)*r.count/top[0].count)
+		c.rect(image.Rect(barStart, y-20, barStart+width, y+20), c.palette.accent)
+		c.text(barStart+width+24, y+18, compact(r.count), 48, true, white)
+	}
+}
+
+type keyCount struct {
+	key   string
+	count int
+}
+
+// topCounts returns the limit keys with the highest counts, ties broken
+// alphabetically.
+func topCounts(counts map[string]int, limit int) []keyCount {
+	top := make([]keyCount, 0, len(counts))
+	for k, n := range counts {
+		if n > 0 {
+			top = append(top, keyCount{k, n})
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].count == top[j].count {
+			return top[i].key < top[j].key
+		}
+		return top[i].count > top[j].count
+	})
+	return top[:min(limit, len(top))]
+}
+
+// compact formats a count the way Telegram shows views, e.g. 12.3K.
+func compact(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%.0fK", float64(n)/1_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+func windowLabel(w analyzer.Window) string {
+	if w.End.IsZero() {
+		return "since " + w.Start.Format("January 2, 2006")
+	}
+	if w.Start.Month() == time.January && w.Start.Day() == 1 && w.End.Equal(w.Start.AddDate(1, 0, 0)) {
+		return fmt.Sprintf("%d Wrapped", w.Start.Year())
+	}
+	return fmt.Sprintf("%s – %s", w.Start.Format("Jan 2, 2006"), w.End.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+}
+
+// squareCrop returns the largest centered square of r.
+func squareCrop(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// circle is an alpha mask of the circle inscribed in a square, with an
+// antialiased edge.
+type circle struct {
+	bounds image.Rectangle
+}
+
+func (c circle) ColorModel() color.Model { return color.AlphaModel }
+
+func (c circle) Bounds() image.Rectangle { return c.bounds }
+
+func (c circle) At(x, y int) color.Color {
+	r := float64(c.bounds.Dx()) / 2
+	dx := float64(x-c.bounds.Min.X) + 0.5 - r
+	dy := float64(y-c.bounds.Min.Y) + 0.5 - r
+	coverage := r - math.Hypot(dx, dy) + 0.5
+	return color.Alpha{uint8(255 * math.Max(0, math.Min(1, coverage)))}
+}
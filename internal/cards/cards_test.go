@@ -0,0 +1,72 @@
+package cards
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+)
+
+func testAnalytics() *analyzer.Analytics {
+	a := analyzer.NewAnalytics("ሰላም Channel")
+	a.SetWindow(analyzer.YearWindow(2025))
+	a.Totals.TotalPosts = 420
+	a.Totals.TotalViews = 1_234_567
+	a.Totals.TotalReactions = 9_876
+	a.Highlights.MostViewedCount = 54_321
+	a.Highlights.MostViewed = analyzer.Message{
+		Text: "እንኳን ደስ አላችሁ! 🎉 Our biggest announcement of the year",
+		Date: time.Date(2025, time.March, 8, 12, 0, 0, 0, time.UTC),
+	}
+	a.Highlights.ReactionsByType["❤️"] = 5_000
+	a.Highlights.ReactionsByType["👍🏽"] = 3_000
+	a.Trends.PostsByHour[18] = 40
+	a.Trends.BestPerformingHour = 18
+	a.Trends.BestPerformingDay = "Tuesday"
+	a.Trends.LongestPostingStreak = 21
+	a.Trends.AverageGapHours = 20.5
+	return &a
+}
+
+func TestRenderSlides(t *testing.T) {
+	a := testAnalytics()
+	for _, slide := range Slides {
+		data, err := Render(a, slide, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", slide, err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: invalid PNG: %v", slide, err)
+		}
+		if b := img.Bounds(); b.Dx() != Width || b.Dy() != Height {
+			t.Fatalf("%s: expected %dx%d, got %v", slide, Width, Height, b)
+		}
+	}
+
+	if _, err := Render(a, "nope", nil); !errors.Is(err, ErrUnknownSlide) {
+		t.Fatalf("expected ErrUnknownSlide, got %v", err)
+	}
+}
+
+func TestFallbackFace(t *testing.T) {
+	fs, err := loadFonts()
+	if err != nil {
+		t.Fatalf("load fonts: %v", err)
+	}
+	face, err := fs.face(32, false)
+	if err != nil {
+		t.Fatalf("face: %v", err)
+	}
+	for _, r := range []rune{'A', 'ሰ', '🎉', '❤'} {
+		if _, ok := face.GlyphAdvance(r); !ok {
+			t.Fatalf("expected a glyph for %q", r)
+		}
+	}
+	if _, ok := face.GlyphAdvance('\ufe0f'); ok {
+		t.Fatalf("expected variation selectors to be skipped")
+	}
+}
@@ -0,0 +1,133 @@
+package cards
+
+import (
+	_ "embed"
+	"fmt"
+	"image"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	//go:embed fonts/NotoSansEthiopic-Regular.ttf
+	ethiopicTTF []byte
+	//go:embed fonts/NotoEmoji-Regular.ttf
+	emojiTTF []byte
+)
+
+type fontSet struct {
+	regular, bold, ethiopic, emoji *opentype.Font
+}
+
+var loadFonts = sync.OnceValues(func() (*fontSet, error) {
+	var fs fontSet
+	for _, f := range []struct {
+		dst  **opentype.Font
+		name string
+		data []byte
+	}{
+		{&fs.regular, "Go Regular", goregular.TTF},
+		{&fs.bold, "Go Bold", gobold.TTF},
+		{&fs.ethiopic, "Noto Sans Ethiopic", ethiopicTTF},
+		{&fs.emoji, "Noto Emoji", emojiTTF},
+	} {
+		parsed, err := opentype.Parse(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", f.name, err)
+		}
+		*f.dst = parsed
+	}
+	return &fs, nil
+})
+
+// face returns a face of the given size that draws Latin text in the Go
+// font and falls back to Ethiopic and emoji glyphs for the other runes.
+func (fs *fontSet) face(size float64, bold bool) (font.Face, error) {
+	primary := fs.regular
+	if bold {
+		primary = fs.bold
+	}
+	faces := make(fallbackFace, 0, 3)
+	for _, f := range []*opentype.Font{primary, fs.ethiopic, fs.emoji} {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, err
+		}
+		faces = append(faces, face)
+	}
+	return faces, nil
+}
+
+// fallbackFace draws every rune with the first face that has a glyph for it.
+// Emoji are drawn in a single color, sequences joined with ZWJ as their
+// separate parts and skin tones in the default tone.
+type fallbackFace []font.Face
+
+// invisible reports the joiners, variation selectors and skin tone
+// modifiers, which only change how the rune before them is presented. They
+// are skipped rather than drawn on their own.
+func invisible(r rune) bool {
+	return r == '\u200d' || (r >= '\ufe00' && r <= '\ufe0f') || (r >= '\U0001f3fb' && r <= '\U0001f3ff')
+}
+
+func (f fallbackFace) faceFor(r rune) (font.Face, bool) {
+	if invisible(r) {
+		return nil, false
+	}
+	for _, face := range f {
+		if _, ok := face.GlyphAdvance(r); ok {
+			return face, true
+		}
+	}
+	return nil, false
+}
+
+func (f fallbackFace) Close() error {
+	for _, face := range f {
+		face.Close()
+	}
+	return nil
+}
+
+func (f fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	face, ok := f.faceFor(r)
+	if !ok {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	return face.Glyph(dot, r)
+}
+
+func (f fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	face, ok := f.faceFor(r)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return face.GlyphBounds(r)
+}
+
+func (f fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	face, ok := f.faceFor(r)
+	if !ok {
+		return 0, false
+	}
+	return face.GlyphAdvance(r)
+}
+
+// Kern only applies between runes of the same face.
+func (f fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	face0, ok0 := f.faceFor(r0)
+	face1, ok1 := f.faceFor(r1)
+	if !ok0 || !ok1 || face0 != face1 {
+		return 0
+	}
+	return face0.Kern(r0, r1)
+}
+
+func (f fallbackFace) Metrics() font.Metrics {
+	return f[0].Metrics()
+}
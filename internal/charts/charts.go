@@ -0,0 +1,341 @@
+// Package charts draws the trends of a wrap as SVG charts, to be served on
+// their own or inlined in a report.
+package charts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 360
+	padLeft     = 64
+	padRight    = 24
+	padTop      = 64
+	padBottom   = 48
+	gridLines   = 4
+
+	cellSize = 14
+	cellStep = 17
+)
+
+// DefaultBrand is the color the data is drawn in without a brand color.
+const DefaultBrand = "#2aabee"
+
+var (
+	ErrUnknownChart = errors.New("unknown chart")
+	ErrInvalidTheme = errors.New("invalid chart theme")
+)
+
+// Charts are the charts of a wrap in the order a report shows them.
+var Charts = []string{"views-by-month", "posts-by-month", "posts-by-hour", "calendar"}
+
+var brandPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Theme is the look of a chart: a light or dark background and the brand
+// color the data is drawn in.
+type Theme struct {
+	Dark  bool
+	Brand string
+}
+
+// ParseTheme reads a theme from its mode, "light" (the default) or "dark",
+// and a hex brand color with or without the leading '#'.
+func ParseTheme(mode, brand string) (Theme, error) {
+	var t Theme
+	switch mode {
+	case "", "light":
+	case "dark":
+		t.Dark = true
+	default:
+		return Theme{}, fmt.Errorf("%w: mode must be light or dark, got %q", ErrInvalidTheme, mode)
+	}
+
+	if brand == "" {
+		t.Brand = DefaultBrand
+		return t, nil
+	}
+	if !brandPattern.MatchString(brand) {
+		return Theme{}, fmt.Errorf("%w: brand must be a hex color, got %q", ErrInvalidTheme, brand)
+	}
+	hex := strings.ToLower(strings.TrimPrefix(brand, "#"))
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	t.Brand = "#" + hex
+	return t, nil
+}
+
+type palette struct {
+	background, text, muted, grid, empty string
+}
+
+func (t Theme) palette() palette {
+	if t.Dark {
+		return palette{background: "#0d1117", text: "#e6edf3", muted: "#8d96a0", grid: "#30363d", empty: "#161b22"}
+	}
+	return palette{background: "#ffffff", text: "#1f2328", muted: "#656d76", grid: "#d0d7de", empty: "#ebedf0"}
+}
+
+func (t Theme) brand() string {
+	if t.Brand == "" {
+		return DefaultBrand
+	}
+	return t.Brand
+}
+
+// Render draws one of the Charts of the wrap as an SVG document.
+func Render(a *analyzer.Analytics, chart string, t Theme) ([]byte, error) {
+	now := time.Now()
+	switch chart {
+	case "views-by-month":
+		labels, values := monthSeries(a.Window, a.Trends.ViewsByMonth, now)
+		return lineChart("Views by month", labels, values, "views", t), nil
+	case "posts-by-month":
+		labels, values := monthSeries(a.Window, a.Trends.PostsByMonth, now)
+		return barChart("Posts by month", labels, values, "posts", t), nil
+	case "posts-by-hour":
+		labels := make([]string, 24)
+		values := make([]int, 24)
+		for h := range labels {
+			labels[h] = fmt.Sprintf("%02d", h)
+			values[h] = a.Trends.PostsByHour[h]
+		}
+		return barChart("Posts by hour", labels, values, "posts", t), nil
+	case "calendar":
+		return calendar(a.Window, a.Trends.PostsByDay, now, t), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownChart, chart)
+	}
+}
+
+// windowEnd is the last day the window covers, today for open windows.
+func windowEnd(w analyzer.Window, now time.Time) time.Time {
+	end := now.In(w.Location())
+	if !w.End.IsZero() && w.End.Before(now) {
+		end = w.End.AddDate(0, 0, -1)
+	}
+	return end
+}
+
+// monthSeries lays the monthly counts out over every month of the window,
+// months without posts included, and keeps months outside of it that have
+// counts.
+func monthSeries(w analyzer.Window, counts map[string]int, now time.Time) ([]string, []int) {
+	first := time.Date(w.Start.Year(), w.Start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := windowEnd(w, now)
+	last := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for key := range counts {
+		month, err := analyzer.ParseMonthKey(key)
+		if err != nil {
+			continue
+		}
+		if month.Before(first) {
+			first = month
+		}
+		if month.After(last) {
+			last = month
+		}
+	}
+
+	multiYear := first.Year() != last.Year()
+	var labels []string
+	var values []int
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		label := m.Format("Jan")
+		if multiYear {
+			label = m.Format("Jan 06")
+		}
+		labels = append(labels, label)
+		values = append(values, counts[analyzer.MonthKey(m)])
+	}
+	return labels, values
+}
+
+// scale returns the top of the value axis, a round number at or above most
+// split into gridLines steps.
+func scale(most int) int {
+	if most <= 0 {
+		return gridLines
+	}
+	raw := float64(most) / gridLines
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 1.5, 2, 2.5, 3, 4, 5, 7.5, 10} {
+		if step := m * magnitude; step >= raw {
+			return int(math.Ceil(step)) * gridLines
+		}
+	}
+	return most
+}
+
+type plot struct {
+	*svg
+	top      int
+	left     float64
+	width    float64
+	height   float64
+	baseline float64
+}
+
+// newPlot draws the frame shared by the bar and line charts: the value axis
+// grid and the category labels, showing at most 12 of them.
+func newPlot(title string, labels []string, values []int, t Theme) *plot {
+	p := &plot{
+		svg:    newSVG(chartWidth, chartHeight, title, t),
+		left:   padLeft,
+		width:  chartWidth - padLeft - padRight,
+		height: chartHeight - padTop - padBottom,
+	}
+	p.baseline = padTop + p.height
+	most := 0
+	for _, v := range values {
+		most = max(most, v)
+	}
+	p.top = scale(most)
+
+	colors := t.palette()
+	for i := 0; i <= gridLines; i++ {
+		y := p.baseline - p.height*float64(i)/gridLines
+		p.line(p.left, y, p.left+p.width, y, colors.grid)
+		p.text(p.left-8, y+4, "end", colors.muted, 12, false, compact(p.top*i/gridLines))
+	}
+
+	every := (len(labels) + 11) / 12
+	slot := p.width / float64(max(1, len(labels)))
+	for i, label := range labels {
+		if i%every == 0 {
+			p.text(p.left+slot*(float64(i)+0.5), p.baseline+20, "middle", colors.muted, 12, false, label)
+		}
+	}
+	return p
+}
+
+func (p *plot) y(v int) float64 {
+	return p.baseline - p.height*float64(v)/float64(p.top)
+}
+
+func barChart(title string, labels []string, values []int, unit string, t Theme) []byte {
+	p := newPlot(title, labels, values, t)
+	slot := p.width / float64(max(1, len(labels)))
+	gap := math.Min(8, slot/4)
+	for i, v := range values {
+		if v == 0 {
+			continue
+		}
+		x := p.left + slot*float64(i) + gap/2
+		y := p.y(v)
+		p.rect(x, y, slot-gap, p.baseline-y, t.brand(), 1, fmt.Sprintf("%s: %d %s", labels[i], v, unit))
+	}
+	return p.bytes()
+}
+
+func lineChart(title string, labels []string, values []int, unit string, t Theme) []byte {
+	p := newPlot(title, labels, values, t)
+	slot := p.width / float64(max(1, len(labels)))
+	points := make([][2]float64, len(values))
+	for i, v := range values {
+		points[i] = [2]float64{p.left + slot*(float64(i)+0.5), p.y(v)}
+	}
+	p.path(points, t.brand(), p.baseline, true)
+	for i, pt := range points {
+		p.circle(pt[0], pt[1], 4, t.brand(), fmt.Sprintf("%s: %d %s", labels[i], values[i], unit))
+	}
+	return p.bytes()
+}
+
+// calendar draws the posts of every day of the window as a heatmap, a column
+// per week and a row per weekday, Sunday first.
+func calendar(w analyzer.Window, postsByDay map[string][]int, now time.Time, t Theme) []byte {
+	colors := t.palette()
+	start := time.Date(w.Start.Year(), w.Start.Month(), w.Start.Day(), 0, 0, 0, 0, time.UTC)
+	end := windowEnd(w, now)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if last.Before(start) {
+		last = start
+	}
+
+	posts := func(d time.Time) int {
+		days := postsByDay[analyzer.MonthKey(d)]
+		if d.Day() > len(days) {
+			return 0
+		}
+		return days[d.Day()-1]
+	}
+	most := 0
+	for d := start; !d.After(last); d = d.AddDate(0, 0, 1) {
+		most = max(most, posts(d))
+	}
+
+	// Weeks start on the Sunday on or before the first day
+	firstSunday := start.AddDate(0, 0, -int(start.Weekday()))
+	weeks := int(last.Sub(firstSunday).Hours()/24)/7 + 1
+	left, top := 40.0, float64(padTop)+8
+	width := int(left) + weeks*cellStep + padRight
+	height := int(top) + 7*cellStep + 48
+
+	s := newSVG(max(width, 360), height, "Posting calendar", t)
+	for _, day := range []time.Weekday{time.Monday, time.Wednesday, time.Friday} {
+		s.text(left-8, top+float64(day)*cellStep+cellSize-3, "end", colors.muted, 11, false, day.String()[:3])
+	}
+
+	for d := start; !d.After(last); d = d.AddDate(0, 0, 1) {
+		week := int(d.Sub(firstSunday).Hours()/24) / 7
+		x := left + float64(week*cellStep)
+		// A window starting late in a month leaves no room for its label
+		if d.Day() == 1 || (d.Equal(start) && d.Day() <= 14) {
+			label := d.Format("Jan")
+			if d.Month() == time.January {
+				label = d.Format("Jan 2006")
+			}
+			s.text(x, top-8, "start", colors.muted, 11, false, label)
+		}
+
+		n := posts(d)
+		fill, opacity := colors.empty, 1.0
+		if n > 0 {
+			fill, opacity = t.brand(), level(n, most)
+		}
+		noun := "posts"
+		if n == 1 {
+			noun = "post"
+		}
+		s.rect(x, top+float64(int(d.Weekday())*cellStep), cellSize, cellSize, fill, opacity,
+			fmt.Sprintf("%s: %d %s", d.Format("Mon, Jan 2, 2006"), n, noun))
+	}
+
+	// Legend
+	y := top + 7*cellStep + 16
+	x := float64(max(width, 360)) - padRight - 5*cellStep - 40
+	s.text(x-6, y+cellSize-3, "end", colors.muted, 11, false, "Less")
+	s.rect(x, y, cellSize, cellSize, colors.empty, 1, "")
+	for i := 1; i <= 4; i++ {
+		s.rect(x+float64(i*cellStep), y, cellSize, cellSize, t.brand(), float64(i)/4, "")
+	}
+	s.text(x+5*cellStep+2, y+cellSize-3, "start", colors.muted, 11, false, "More")
+	return s.bytes()
+}
+
+// level returns the opacity of a day in the heatmap, one of four steps
+// relative to the busiest day.
+func level(n, most int) float64 {
+	return math.Ceil(4*float64(n)/float64(most)) / 4
+}
+
+// compact formats an axis value, e.g. 12K.
+func compact(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000_000), ".0") + "M"
+	case n >= 1_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000), ".0") + "K"
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
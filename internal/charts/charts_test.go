@@ -0,0 +1,84 @@
+package charts
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme("dark", "F50")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !theme.Dark || theme.Brand != "#ff5500" {
+		t.Fatalf("expected a dark #ff5500 theme, got %+v", theme)
+	}
+	if theme, _ := ParseTheme("", ""); theme.Dark || theme.Brand != DefaultBrand {
+		t.Fatalf("expected the default light theme, got %+v", theme)
+	}
+	for _, tt := range [][2]string{{"sepia", ""}, {"light", "blue"}, {"", "#12345"}} {
+		if _, err := ParseTheme(tt[0], tt[1]); !errors.Is(err, ErrInvalidTheme) {
+			t.Fatalf("%q: expected ErrInvalidTheme, got %v", tt, err)
+		}
+	}
+}
+
+func TestMonthSeriesFillsGaps(t *testing.T) {
+	counts := map[string]int{"2024-February": 3, "2024-May": 7}
+	labels, values := monthSeries(analyzer.YearWindow(2024), counts, time.Now())
+	if len(labels) != 12 || labels[0] != "Jan" || labels[11] != "Dec" {
+		t.Fatalf("expected every month of 2024, got %v", labels)
+	}
+	if values[1] != 3 || values[4] != 7 || values[2] != 0 {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := map[int]int{0: 4, 3: 4, 9: 12, 12: 12, 87: 100, 1234: 1600}
+	for most, want := range tests {
+		if got := scale(most); got != want {
+			t.Fatalf("scale(%d): expected %d, got %d", most, want, got)
+		}
+	}
+}
+
+func TestRenderIsValidSVG(t *testing.T) {
+	a := analyzer.NewAnalytics("channel")
+	a.SetWindow(analyzer.YearWindow(2024))
+	a.Trends.PostsByMonth["2024-March"] = 12
+	a.Trends.ViewsByMonth["2024-March"] = 3400
+	a.Trends.PostsByHour[9] = 5
+	a.Trends.PostsByDay["2024-March"] = make([]int, 31)
+	a.Trends.PostsByDay["2024-March"][4] = 2
+
+	theme := Theme{Dark: true, Brand: "#ff5500"}
+	for _, chart := range Charts {
+		data, err := Render(&a, chart, theme)
+		if err != nil {
+			t.Fatalf("%s: %v", chart, err)
+		}
+		if !bytes.HasPrefix(data, []byte("<svg")) || !strings.Contains(string(data), "#ff5500") {
+			t.Fatalf("%s: expected an svg in the brand color", chart)
+		}
+		d := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: invalid XML: %v", chart, err)
+			}
+		}
+	}
+
+	if _, err := Render(&a, "pie", theme); !errors.Is(err, ErrUnknownChart) {
+		t.Fatalf("expected ErrUnknownChart, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package charts
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const fontFamily = "-apple-system, 'Segoe UI', 'Noto Sans', 'Noto Sans Ethiopic', Helvetica, Arial, sans-serif"
+
+// svg writes a standalone SVG document. It has no XML declaration so the
+// output can also be inlined in an HTML page.
+type svg struct {
+	b     strings.Builder
+	theme Theme
+}
+
+func newSVG(width, height int, title string, t Theme) *svg {
+	s := &svg{theme: t}
+	fmt.Fprintf(&s.b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" role="img" aria-label="%s" font-family="%s">`,
+		width, height, width, height, html.EscapeString(title), html.EscapeString(fontFamily))
+	fmt.Fprintf(&s.b, `<title>%s</title>`, html.EscapeString(title))
+	s.rect(0, 0, float64(width), float64(height), t.palette().background, 1, "")
+	s.text(padLeft, 32, "start", t.palette().text, 18, true, title)
+	return s
+}
+
+func (s *svg) rect(x, y, w, h float64, fill string, opacity float64, tooltip string) {
+	fmt.Fprintf(&s.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"`, x, y, w, h, fill)
+	if opacity < 1 {
+		fmt.Fprintf(&s.b, ` fill-opacity="%.2f"`, opacity)
+	}
+	s.close("rect", tooltip)
+}
+
+func (s *svg) circle(cx, cy, r float64, fill, tooltip string) {
+	fmt.Fprintf(&s.b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"`, cx, cy, r, fill)
+	s.close("circle", tooltip)
+}
+
+func (s *svg) line(x1, y1, x2, y2 float64, stroke string) {
+	fmt.Fprintf(&s.b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="1"/>`, x1, y1, x2, y2, stroke)
+}
+
+// path draws the points joined by straight lines, filled with opacity below
+// the line down to baseline when fill is set.
+func (s *svg) path(points [][2]float64, stroke string, baseline float64, fill bool) {
+	if len(points) == 0 {
+		return
+	}
+	var d strings.Builder
+	for i, p := range points {
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		fmt.Fprintf(&d, "%s%.1f %.1f ", cmd, p[0], p[1])
+	}
+	if fill {
+		last, first := points[len(points)-1], points[0]
+		fmt.Fprintf(&s.b, `<path d="%sL%.1f %.1f L%.1f %.1f Z" fill="%s" fill-opacity="0.15"/>`,
+			d.String(), last[0], baseline, first[0], baseline, stroke)
+	}
+	fmt.Fprintf(&s.b, `<path d="%s" fill="none" stroke="%s" stroke-width="2.5" stroke-linejoin="round"/>`,
+		strings.TrimSpace(d.String()), stroke)
+}
+
+func (s *svg) text(x, y float64, anchor, fill string, size int, bold bool, content string) {
+	weight := ""
+	if bold {
+		weight = ` font-weight="bold"`
+	}
+	fmt.Fprintf(&s.b, `<text x="%.1f" y="%.1f" text-anchor="%s" fill="%s" font-size="%d"%s>%s</text>`,
+		x, y, anchor, fill, size, weight, html.EscapeString(content))
+}
+
+// close ends a shape, with a tooltip shown on hover when set.
+func (s *svg) close(element, tooltip string) {
+	if tooltip == "" {
+		s.b.WriteString("/>")
+		return
+	}
+	fmt.Fprintf(&s.b, `><title>%s</title></%s>`, html.EscapeString(tooltip), element)
+}
+
+func (s *svg) bytes() []byte {
+	s.b.WriteString("</svg>")
+	return []byte(s.b.String())
+}
@@ -18,6 +18,8 @@ var (
 	ErrMinioConnection = errors.New("minio connection failed")
 	ErrTelegramAPI     = errors.New("telegram API error")
 	ErrRedisConnection = errors.New("redis connection failed")
+	ErrInvalidWindow   = errors.New("invalid analysis window")
+	ErrObjectNotFound  = errors.New("object not found")
 )
 
 type AnalyzerError struct {
@@ -0,0 +1,156 @@
+// Package langid identifies the language of short texts offline. Amharic is
+// recognized by its Ethiopic script, Latin script texts are told apart by
+// comparing their character trigrams against embedded language profiles.
+package langid
+
+import (
+	"bufio"
+	"embed"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed profiles/*.txt
+var profileFiles embed.FS
+
+const (
+	Amharic = "am"
+	English = "en"
+	Oromo   = "om"
+	// Unknown is returned for texts without letters or in other scripts.
+	Unknown = "und"
+
+	// minLetters is the amount of Latin letters below which trigrams are too
+	// few to tell languages apart.
+	minLetters = 3
+)
+
+// latinLanguages are the languages with a trigram profile.
+var latinLanguages = []string{English, Oromo}
+
+type profile struct {
+	ranks map[string]int
+	size  int
+}
+
+var profiles = loadProfiles()
+
+func loadProfiles() map[string]profile {
+	loaded := make(map[string]profile)
+	for _, language := range latinLanguages {
+		f, err := profileFiles.Open("profiles/" + language + ".txt")
+		if err != nil {
+			panic(err)
+		}
+		p := profile{ranks: make(map[string]int)}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			trigram := strings.TrimSpace(scanner.Text())
+			if trigram == "" || strings.HasPrefix(trigram, "#") {
+				continue
+			}
+			p.ranks[trigram] = p.size
+			p.size++
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+		loaded[language] = p
+	}
+	return loaded
+}
+
+// Result is the detected language with a confidence in [0, 1].
+type Result struct {
+	Language   string
+	Confidence float64
+}
+
+// Detect returns the language of text. Scripts decide first, the trigram
+// profiles are only consulted for texts written mostly in Latin script.
+func Detect(text string) Result {
+	ethiopic, latin, other := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Ethiopic, r):
+			ethiopic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.IsLetter(r):
+			other++
+		}
+	}
+	letters := ethiopic + latin + other
+	if letters == 0 {
+		return Result{Language: Unknown}
+	}
+	switch {
+	case ethiopic >= latin && ethiopic >= other:
+		return Result{Language: Amharic, Confidence: float64(ethiopic) / float64(letters)}
+	case other > latin || latin < minLetters:
+		return Result{Language: Unknown}
+	}
+
+	trigrams := Trigrams(text)
+	type scored struct {
+		language string
+		distance int
+	}
+	scores := make([]scored, 0, len(latinLanguages))
+	for _, language := range latinLanguages {
+		scores = append(scores, scored{language, distance(trigrams, profiles[language])})
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].distance < scores[j].distance })
+
+	best := scores[0]
+	confidence := 1.0
+	if runnerUp := scores[1].distance; runnerUp != 0 {
+		confidence = float64(runnerUp-best.distance) / float64(runnerUp)
+	}
+	return Result{Language: best.language, Confidence: confidence * float64(latin) / float64(letters)}
+}
+
+// Trigrams returns the trigrams of text ranked by frequency, each word padded
+// with _ on both sides so word boundaries count.
+func Trigrams(text string) []string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		padded := []rune("_" + word + "_")
+		for i := 0; i+3 <= len(padded); i++ {
+			counts[string(padded[i:i+3])]++
+		}
+	}
+	ranked := make([]string, 0, len(counts))
+	for trigram := range counts {
+		ranked = append(ranked, trigram)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if counts[ranked[i]] == counts[ranked[j]] {
+			return ranked[i] < ranked[j]
+		}
+		return counts[ranked[i]] > counts[ranked[j]]
+	})
+	return ranked
+}
+
+// distance is the out-of-place measure of Cavnar and Trenkle: how far each
+// trigram of the text is from its rank in the profile, trigrams missing from
+// the profile costing the profile size.
+func distance(ranked []string, p profile) int {
+	total := 0
+	for rank, trigram := range ranked {
+		profileRank, ok := p.ranks[trigram]
+		if !ok {
+			total += p.size
+			continue
+		}
+		if rank > profileRank {
+			total += rank - profileRank
+		} else {
+			total += profileRank - rank
+		}
+	}
+	return total
+}
@@ -0,0 +1,23 @@
+package langid
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"ሰላም ለሁላችሁ! ዛሬ አዲስ ዜና ይዘን ቀርበናል።", Amharic},
+		{"The minister announced a new road project for the city today.", English},
+		{"Barattootni hedduun har'a mana barumsaatti deebi'aniiru.", Oromo},
+		{"Galatoomaa, odeeffannoo dabalataaf nu hordofaa", Oromo},
+		{"Thank you all for the support, see you tomorrow", English},
+		{"Добрый день", Unknown},
+		{"12:30 🎉", Unknown},
+	}
+	for _, c := range cases {
+		if got := Detect(c.text); got.Language != c.want {
+			t.Fatalf("Detect(%q) = %+v, want %s", c.text, got, c.want)
+		}
+	}
+}
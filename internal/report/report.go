@@ -0,0 +1,286 @@
+// Package report renders a wrap as a single self-contained HTML page. Styles,
+// charts and pictures are inlined so the file can be archived or emailed and
+// opened without the service.
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/charts"
+)
+
+const (
+	topItems      = 10
+	snippetLength = 400
+)
+
+//go:embed report.html
+var pageSource string
+
+var page = template.Must(template.New("report").Parse(pageSource))
+
+// languageNames names the languages langid detects, undetermined posts are
+// left out.
+var languageNames = map[string]string{
+	"am": "Amharic",
+	"en": "English",
+	"om": "Afaan Oromo",
+}
+
+// ImageFunc reads a stored picture by the profile URL the analytics hold.
+type ImageFunc func(profile string) ([]byte, error)
+
+type Options struct {
+	Theme charts.Theme
+	// Image embeds the profile pictures, without it they are left out
+	Image ImageFunc
+	// GeneratedAt is shown in the footer, the current time when zero
+	GeneratedAt time.Time
+}
+
+type stat struct {
+	Label, Value string
+}
+
+type count struct {
+	Label string
+	Count string
+}
+
+type post struct {
+	Title, Value, Date, Text string
+}
+
+type source struct {
+	Name, Username, Forwards string
+	Picture                  template.URL
+}
+
+type view struct {
+	Dark        bool
+	Brand       template.CSS
+	Title       string
+	Username    string
+	Window      string
+	Subscribers string
+	Picture     template.URL
+	Initial     string
+	Totals      []stat
+	Posts       []post
+	Source      *source
+	Charts      []template.HTML
+	Emojis      []count
+	Hashtags    []count
+	Content     []count
+	Languages   []count
+	Style       []stat
+	GeneratedAt string
+}
+
+// Filename names the report of a channel window, e.g.
+// "durov-wrapped-2025-01-01_now.html", keeping only characters safe in file
+// names and headers.
+func Filename(username string, window analyzer.Window) string {
+	name := fmt.Sprintf("%s-wrapped-%s", strings.TrimPrefix(username, "@"), window.Key())
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		case r == '@':
+			return '_'
+		default:
+			return '-'
+		}
+	}, name)
+	return name + ".html"
+}
+
+// Render writes the report of the wrap as an HTML document.
+func Render(a *analyzer.Analytics, opts Options) ([]byte, error) {
+	generatedAt := opts.GeneratedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+
+	v := view{
+		Dark:        opts.Theme.Dark,
+		Brand:       template.CSS(brand(opts.Theme)),
+		Title:       a.ChannelName,
+		Username:    a.Info.Username,
+		Window:      windowLabel(a.Window),
+		Picture:     dataURL(opts.Image, a.ChannelProfile),
+		Initial:     "#",
+		GeneratedAt: generatedAt.In(a.Window.Location()).Format("January 2, 2006 at 15:04 MST"),
+	}
+	for _, r := range a.ChannelName {
+		v.Initial = strings.ToUpper(string(r))
+		break
+	}
+	if a.Info.Subscribers != 0 {
+		v.Subscribers = thousands(a.Info.Subscribers)
+	}
+
+	v.Totals = []stat{
+		{"Posts", thousands(a.Totals.TotalPosts)},
+		{"Views", thousands(a.Totals.TotalViews)},
+		{"Reactions", thousands(a.Totals.TotalReactions)},
+		{"Comments", thousands(a.Totals.TotalComments)},
+		{"Shares", thousands(a.Engagement.TotalShares)},
+		{"Engagement rate", fmt.Sprintf("%.2f%%", a.Engagement.EngagementRate*100)},
+		{"Longest streak", days(a.Trends.LongestPostingStreak)},
+	}
+	if a.Trends.BestPerformingHour >= 0 && a.Trends.BestPerformingDay != "" {
+		v.Totals = append(v.Totals, stat{"Best time to post", fmt.Sprintf("%ss at %02d:00", a.Trends.BestPerformingDay, a.Trends.BestPerformingHour)})
+	}
+
+	location := a.Window.Location()
+	if a.Highlights.MostViewedCount != 0 {
+		v.Posts = append(v.Posts, newPost("Most viewed post", thousands(a.Highlights.MostViewedCount)+" views", a.Highlights.MostViewed, location))
+	}
+	if a.Highlights.MostCommentedCount != 0 {
+		v.Posts = append(v.Posts, newPost("Most commented post", thousands(a.Highlights.MostCommentedCount)+" comments", a.Highlights.MostCommented, location))
+	}
+	if src := a.Highlights.MostForwardedSource; src.Name != "" {
+		v.Source = &source{
+			Name:     src.Name,
+			Username: src.Username,
+			Forwards: thousands(src.ForwardsCount),
+			Picture:  dataURL(opts.Image, src.Profile),
+		}
+	}
+
+	for _, chart := range charts.Charts {
+		svg, err := charts.Render(a, chart, opts.Theme)
+		if err != nil {
+			return nil, err
+		}
+		// The charts escape their labels themselves
+		v.Charts = append(v.Charts, template.HTML(svg))
+	}
+
+	for _, e := range a.Emojis.TopEmojis[:min(topItems, len(a.Emojis.TopEmojis))] {
+		v.Emojis = append(v.Emojis, count{e.Emoji, thousands(e.Count)})
+	}
+	for _, h := range a.Content.TopHashtags[:min(topItems, len(a.Content.TopHashtags))] {
+		v.Hashtags = append(v.Hashtags, count{h.Hashtag, thousands(h.Count)})
+	}
+
+	v.Content = sortedCounts(a.Content.PostsByType)
+	languages := make(map[string]int, len(a.Languages.PostsByLanguage))
+	for code, posts := range a.Languages.PostsByLanguage {
+		if name, ok := languageNames[code]; ok {
+			languages[name] = posts
+		}
+	}
+	v.Languages = sortedCounts(languages)
+	if a.Sentiment.ScoredPosts != 0 {
+		d := a.Sentiment.Distribution
+		v.Style = append(v.Style, stat{"Mood", fmt.Sprintf("%d positive, %d neutral, %d negative", d.Positive, d.Neutral, d.Negative)})
+	}
+	if a.Topics.MostDiscussed != "" {
+		v.Style = append(v.Style, stat{"Most discussed topic", a.Topics.MostDiscussed})
+	}
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newPost(title, value string, m analyzer.Message, location *time.Location) post {
+	p := post{Title: title, Value: value}
+	if !m.Date.IsZero() {
+		p.Date = m.Date.In(location).Format("January 2, 2006 15:04")
+	}
+	text := strings.TrimSpace(m.Text)
+	if runes := []rune(text); len(runes) > snippetLength {
+		text = string(runes[:snippetLength]) + "…"
+	}
+	p.Text = text
+	return p
+}
+
+// dataURL embeds a stored picture, empty when there is none or it cannot be
+// read so the page falls back to a placeholder.
+func dataURL(image ImageFunc, profile string) template.URL {
+	if image == nil || profile == "" {
+		return ""
+	}
+	data, err := image(profile)
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return ""
+	}
+	return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+func brand(t charts.Theme) string {
+	if t.Brand == "" {
+		return charts.DefaultBrand
+	}
+	return t.Brand
+}
+
+// sortedCounts orders the counts from the highest, ties broken
+// alphabetically.
+func sortedCounts(counts map[string]int) []count {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] == counts[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return counts[keys[i]] > counts[keys[j]]
+	})
+	sorted := make([]count, len(keys))
+	for i, k := range keys {
+		sorted[i] = count{k, thousands(counts[k])}
+	}
+	return sorted
+}
+
+// thousands formats a count with thousands separators, e.g. 1,234,567.
+func thousands(n int) string {
+	s := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+func days(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+func windowLabel(w analyzer.Window) string {
+	if w.End.IsZero() {
+		return "Since " + w.Start.Format("January 2, 2006")
+	}
+	if w.Start.Month() == time.January && w.Start.Day() == 1 && w.End.Equal(w.Start.AddDate(1, 0, 0)) {
+		return strconv.Itoa(w.Start.Year())
+	}
+	return fmt.Sprintf("%s – %s", w.Start.Format("January 2, 2006"), w.End.AddDate(0, 0, -1).Format("January 2, 2006"))
+}
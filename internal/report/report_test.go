@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/charts"
+)
+
+func TestRenderIsSelfContained(t *testing.T) {
+	a := analyzer.NewAnalytics("ሰላም <Channel>")
+	a.SetWindow(analyzer.YearWindow(2024))
+	a.ChannelProfile = "profile.png"
+	a.Totals.TotalViews = 1234567
+	a.Highlights.MostViewedCount = 4200
+	a.Highlights.MostViewed = analyzer.Message{
+		Text: "<script>alert(1)</script> 🎉",
+		Date: time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC),
+	}
+	a.Highlights.MostForwardedSource = analyzer.ForwardSource{Name: "Source", Profile: "missing.jpg", ForwardsCount: 3}
+
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	images := func(objectName string) ([]byte, error) {
+		if objectName == "profile.png" {
+			return picture.Bytes(), nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	data, err := Render(&a, Options{Theme: charts.Theme{Dark: true, Brand: "#ff5500"}, Image: images})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	html := string(data)
+
+	if n := strings.Count(html, "<svg"); n != len(charts.Charts) {
+		t.Fatalf("expected %d inline charts, got %d", len(charts.Charts), n)
+	}
+	if !strings.Contains(html, `src="data:image/png;base64,`) {
+		t.Fatalf("expected the profile picture to be embedded")
+	}
+	if strings.Contains(html, "<script>") || strings.Contains(html, "ZgotmplZ") {
+		t.Fatalf("expected the post text to be escaped and the values to be allowed")
+	}
+	if !strings.Contains(html, "--brand: #ff5500") || !strings.Contains(html, "1,234,567") {
+		t.Fatalf("expected the brand color and formatted totals")
+	}
+	for _, external := range []string{`src="http`, `href="http`, "<link", "@import"} {
+		if strings.Contains(html, external) {
+			t.Fatalf("expected no external resources, found %q", external)
+		}
+	}
+}
+
+func TestFilename(t *testing.T) {
+	window, err := analyzer.YearWindow(2024).In("Africa/Addis_Ababa")
+	if err != nil {
+		t.Fatalf("window: %v", err)
+	}
+	if got, want := Filename("@durov", window), "durov-wrapped-2024-01-01_2025-01-01_Africa-Addis_Ababa.html"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestThousands(t *testing.T) {
+	tests := map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567", -12345: "-12,345"}
+	for n, want := range tests {
+		if got := thousands(n); got != want {
+			t.Fatalf("thousands(%d): expected %q, got %q", n, want, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package scheduler runs background jobs on a schedule inside the server
+// process.
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+// Job is one run of a scheduled task, its error is logged and the job keeps
+// its schedule.
+type Job func(ctx context.Context) error
+
+// Schedule returns the next time a job runs after t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// Every runs a job at a fixed interval, the first run one interval after the
+// scheduler starts.
+func Every(d time.Duration) Schedule {
+	return interval(d)
+}
+
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+	cancel   context.CancelFunc
+}
+
+// Scheduler runs each job in its own goroutine. Runs of the same job never
+// overlap, a run that overlaps the next slot delays it.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	wg      sync.WaitGroup
+	ctx     context.Context
+}
+
+func New() *Scheduler {
+	return &Scheduler{entries: make(map[string]*entry)}
+}
+
+// Add registers a job, replacing the job of the same name. Jobs added after
+// Start begin right away.
+func (s *Scheduler) Add(name string, schedule Schedule, job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, ok := s.entries[name]; ok && previous.cancel != nil {
+		previous.cancel()
+	}
+	e := &entry{name: name, schedule: schedule, job: job}
+	s.entries[name] = e
+	if s.ctx != nil {
+		s.run(s.ctx, e)
+	}
+}
+
+// Remove stops a job, a run in progress is cancelled through its context.
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[name]; ok {
+		if e.cancel != nil {
+			e.cancel()
+		}
+		delete(s.entries, name)
+	}
+}
+
+// Start runs the registered jobs until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	for _, e := range s.entries {
+		s.run(ctx, e)
+	}
+	logger.Info("Scheduler started", "jobs", len(s.entries))
+}
+
+// Wait blocks until every job goroutine returned after ctx is done.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		log := logger.With("operation", "ScheduledJob", "job", e.name)
+
+		for {
+			next := e.schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Warn("Schedule has no next run, job stopped")
+				return
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Info("Scheduled job stopped")
+				return
+			case <-timer.C:
+			}
+
+			start := time.Now()
+			if err := e.job(ctx); err != nil {
+				log.Error("Scheduled job failed", "error", err, "duration", time.Since(start))
+				continue
+			}
+			log.Info("Scheduled job completed", "duration", time.Since(start))
+		}
+	}()
+}
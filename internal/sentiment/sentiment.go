@@ -0,0 +1,232 @@
+package sentiment
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//go:embed lexicons/*.tsv
+var lexiconFiles embed.FS
+
+const (
+	// normalizationAlpha approximates the maximum expected raw score, it maps the
+	// raw valence sum into [-1, 1] the same way VADER does.
+	normalizationAlpha = 15
+	neutralThreshold   = 0.05
+	negationFactor     = -0.75
+)
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "none": true, "nothing": true,
+	"dont": true, "don't": true, "isnt": true, "isn't": true, "wasnt": true,
+	"wasn't": true, "cant": true, "can't": true, "wont": true, "won't": true,
+	"without": true,
+}
+
+// postNegations negate the word before them, as Amharic places the negated
+// copula after the predicate, e.g. "ጥሩ አይደለም" (not good).
+var postNegations = map[string]bool{
+	"አይደለም": true, "አይደለችም": true, "አይደሉም": true, "አይደለሁም": true,
+	"አይደለንም": true, "አይደለህም": true, "የለም": true, "የለውም": true,
+	"የላትም": true, "የሉም": true,
+}
+
+// embeddedLanguages are the lexicons every scorer built with New starts from.
+var embeddedLanguages = []string{"en", "am"}
+
+// Scorer assigns a sentiment score in [-1, 1] to a piece of text, negative
+// scores being negative sentiment.
+type Scorer interface {
+	Score(text string) float64
+}
+
+type Label string
+
+const (
+	Positive Label = "positive"
+	Neutral  Label = "neutral"
+	Negative Label = "negative"
+)
+
+// Classify buckets a score into a label, scores close to zero are neutral.
+func Classify(score float64) Label {
+	switch {
+	case score >= neutralThreshold:
+		return Positive
+	case score <= -neutralThreshold:
+		return Negative
+	default:
+		return Neutral
+	}
+}
+
+// Lexicon maps lowercased words to their valence.
+type Lexicon map[string]float64
+
+// ParseLexicon reads a lexicon in "word<TAB>valence" format, one entry per
+// line. Blank lines and lines starting with # are skipped.
+func ParseLexicon(r io.Reader) (Lexicon, error) {
+	lexicon := make(Lexicon)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("lexicon line %d: expected word and valence separated by a tab", line)
+		}
+		valence, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("lexicon line %d: invalid valence: %v", line, err)
+		}
+		lexicon[strings.ToLower(strings.TrimSpace(fields[0]))] = valence
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lexicon, nil
+}
+
+// LoadLexiconFile reads a custom lexicon in the same format as ParseLexicon.
+func LoadLexiconFile(path string) (Lexicon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lexicon, err := ParseLexicon(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lexicon, nil
+}
+
+// Embedded returns one of the lexicons shipped with the package by language
+// code, "en" or "am".
+func Embedded(language string) (Lexicon, error) {
+	f, err := lexiconFiles.Open("lexicons/" + language + ".tsv")
+	if err != nil {
+		return nil, fmt.Errorf("no embedded lexicon for %q: %w", language, err)
+	}
+	defer f.Close()
+	return ParseLexicon(f)
+}
+
+// LexiconScorer sums the valence of known words, flipping the words that
+// directly follow a negation or directly precede a post negation, and
+// normalizes the sum into [-1, 1].
+type LexiconScorer struct {
+	lexicon Lexicon
+}
+
+// NewLexiconScorer merges the given lexicons, later ones taking precedence.
+func NewLexiconScorer(lexicons ...Lexicon) *LexiconScorer {
+	merged := make(Lexicon)
+	for _, lexicon := range lexicons {
+		for word, valence := range lexicon {
+			merged[word] = valence
+		}
+	}
+	return &LexiconScorer{lexicon: merged}
+}
+
+func (s *LexiconScorer) Score(text string) float64 {
+	sum := 0.0
+	negated := false
+	previous := 0.0
+	for _, token := range Tokenize(text) {
+		if negations[token] {
+			negated = true
+			previous = 0
+			continue
+		}
+		if postNegations[token] {
+			sum += previous*negationFactor - previous
+			previous = 0
+			continue
+		}
+		valence, ok := s.lexicon[token]
+		if !ok {
+			previous = 0
+			continue
+		}
+		if negated {
+			valence *= negationFactor
+			negated = false
+		}
+		sum += valence
+		previous = valence
+	}
+	if sum == 0 {
+		return 0
+	}
+	return sum / math.Sqrt(sum*sum+normalizationAlpha)
+}
+
+// New returns a scorer backed by the embedded English and Amharic lexicons
+// and the custom lexicon files at paths, later files taking precedence.
+func New(paths ...string) (Scorer, error) {
+	lexicons := make([]Lexicon, 0, len(embeddedLanguages)+len(paths))
+	for _, language := range embeddedLanguages {
+		lexicon, err := Embedded(language)
+		if err != nil {
+			return nil, err
+		}
+		lexicons = append(lexicons, lexicon)
+	}
+	for _, path := range paths {
+		lexicon, err := LoadLexiconFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lexicons = append(lexicons, lexicon)
+	}
+	return NewLexiconScorer(lexicons...), nil
+}
+
+// Default returns a scorer backed by the embedded lexicons only.
+func Default() Scorer {
+	scorer, err := New()
+	if err != nil {
+		panic(err)
+	}
+	return scorer
+}
+
+// Tokenize splits text into lowercased words, keeping apostrophes inside words
+// so contractions such as "don't" survive.
+func Tokenize(text string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, strings.Trim(current.String(), "'"))
+			current.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r):
+			current.WriteRune(r)
+		case r == '\'' || r == '’':
+			if current.Len() > 0 {
+				current.WriteRune('\'')
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
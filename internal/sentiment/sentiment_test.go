@@ -0,0 +1,152 @@
+package sentiment
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	scorer := Default()
+
+	cases := []struct {
+		text string
+		want Label
+	}{
+		{"What a great and wonderful day", Positive},
+		{"This is not good", Negative},
+		{"ዛሬ ጥሩ ቀን ነው።", Positive},
+		{"ይህ ጥሩ አይደለም።", Negative},
+		{"ጦርነት እና ሞት", Negative},
+		{"The meeting is at noon", Neutral},
+	}
+	for _, c := range cases {
+		if got := Classify(scorer.Score(c.text)); got != c.want {
+			t.Fatalf("Classify(Score(%q)) = %s, want %s", c.text, got, c.want)
+		}
+	}
+}
+
+func TestCustomLexicon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.tsv")
+	if err := os.WriteFile(path, []byte("# domain words\nbullish\t3\ngood\t-1\n"), 0o644); err != nil {
+		t.Fatalf("failed to write lexicon: %v", err)
+	}
+
+	scorer, err := New(path)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if Classify(scorer.Score("very bullish")) != Positive {
+		t.Fatalf("custom word was not scored")
+	}
+	if Classify(scorer.Score("good")) != Negative {
+		t.Fatalf("custom lexicon should override the embedded valence")
+	}
+
+	if _, err := ParseLexicon(strings.NewReader("broken line")); err == nil {
+		t.Fatalf("expected error for a line without a valence")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"Hello, WORLD!", []string{"hello", "world"}},
+		{"I don't like it", []string{"i", "don't", "like", "it"}},
+		{"'quoted' words’", []string{"quoted", "words"}},
+		{"won’t stop 2day", []string{"won't", "stop", "2day"}},
+		{"ዛሬ ጥሩ ቀን ነው።", []string{"ዛሬ", "ጥሩ", "ቀን", "ነው"}},
+	}
+	for _, c := range cases {
+		if got := Tokenize(c.text); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("Tokenize(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		score float64
+		want  Label
+	}{
+		{0, Neutral},
+		{0.049, Neutral},
+		{-0.049, Neutral},
+		{0.05, Positive},
+		{-0.05, Negative},
+		{1, Positive},
+		{-1, Negative},
+	}
+	for _, c := range cases {
+		if got := Classify(c.score); got != c.want {
+			t.Fatalf("Classify(%v) = %s, want %s", c.score, got, c.want)
+		}
+	}
+}
+
+func TestLexiconScorer(t *testing.T) {
+	normalized := func(sum float64) float64 {
+		return sum / math.Sqrt(sum*sum+normalizationAlpha)
+	}
+	scorer := NewLexiconScorer(
+		Lexicon{"good": 3, "bad": -2, "okay": 1},
+		// Later lexicons take precedence
+		Lexicon{"okay": 0.5},
+	)
+
+	cases := []struct {
+		text string
+		want float64
+	}{
+		{"", 0},
+		{"nothing known here", 0},
+		{"good", normalized(3)},
+		{"Good, GOOD", normalized(6)},
+		{"good bad", normalized(1)},
+		{"okay", normalized(0.5)},
+		// A negation only flips the next known word
+		{"not good", normalized(3 * negationFactor)},
+		{"not good good", normalized(3*negationFactor + 3)},
+		{"never bad", normalized(-2 * negationFactor)},
+	}
+	for _, c := range cases {
+		if got := scorer.Score(c.text); math.Abs(got-c.want) > 1e-9 {
+			t.Fatalf("Score(%q) = %v, want %v", c.text, got, c.want)
+		}
+	}
+
+	long := strings.Repeat("good ", 1000)
+	if got := scorer.Score(long); got <= 0.99 || got > 1 {
+		t.Fatalf("Score of a long positive text = %v, want close to but not above 1", got)
+	}
+	if got := scorer.Score(strings.Repeat("bad ", 1000)); got >= -0.99 || got < -1 {
+		t.Fatalf("Score of a long negative text = %v, want close to but not below -1", got)
+	}
+}
+
+func TestParseLexicon(t *testing.T) {
+	lexicon, err := ParseLexicon(strings.NewReader("# comment\n\n  Great\t2.5 \nawful\t-3\n"))
+	if err != nil {
+		t.Fatalf("ParseLexicon returned error: %v", err)
+	}
+	if want := (Lexicon{"great": 2.5, "awful": -3}); !reflect.DeepEqual(lexicon, want) {
+		t.Fatalf("ParseLexicon = %v, want %v", lexicon, want)
+	}
+
+	if _, err := ParseLexicon(strings.NewReader("good\tvery")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected a line 1 error for an invalid valence, got %v", err)
+	}
+	if _, err := LoadLexiconFile(filepath.Join(t.TempDir(), "missing.tsv")); err == nil {
+		t.Fatalf("expected error for a missing lexicon file")
+	}
+	if _, err := Embedded("xx"); err == nil {
+		t.Fatalf("expected error for a language without an embedded lexicon")
+	}
+}
@@ -1,20 +1,123 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
 	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
 	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+	"github.com/hunderaweke/tg-unwrapped/internal/webhook"
 )
 
+const maxCompareChannels = 20
+
+// WindowRequest is read from the JSON body, or from the query string by the
+// GET endpoints.
+type WindowRequest struct {
+	Year     int    `json:"year,omitempty" form:"year"`
+	From     string `json:"from,omitempty" form:"from"`
+	To       string `json:"to,omitempty" form:"to"`
+	Timezone string `json:"timezone,omitempty" form:"timezone"`
+}
+
+func (w WindowRequest) Window() (analyzer.Window, error) {
+	window, err := analyzer.ParseWindow(w.Year, w.From, w.To)
+	if err != nil {
+		return analyzer.Window{}, err
+	}
+	return window.In(w.Timezone)
+}
+
 type AnalyticsRequest struct {
 	Username string `json:"username,omitempty"`
+	// CallbackURL makes the request asynchronous, the outcome is posted to it
+	CallbackURL string `json:"callback_url,omitempty"`
+	WindowRequest
+}
+
+// normalizeUsername matches the keys of the snapshot, velocity and tracking
+// stores, so "@Channel" and "channel" share a cache entry.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+func cacheKey(username string, window analyzer.Window) string {
+	if window.IsDefault() {
+		return normalizeUsername(username)
+	}
+	return fmt.Sprintf("%s:%s", normalizeUsername(username), window.Key())
 }
 
-func AnalyticsHandler(redisService *storage.RedisService, minioClient *storage.MinioClient) gin.HandlerFunc {
+// LoadAnalytics returns the cached analytics for the window, falling back to
+// the latest snapshot for windows that already ended, and computes them
+// otherwise. Freshly computed analytics are cached and snapshotted.
+func LoadAnalytics(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store, username string, window analyzer.Window) (*analyzer.Analytics, error) {
+	log := logger.With("operation", "LoadAnalytics", "username", username, "window", window.Key())
+
+	var analytics *analyzer.Analytics
+	ok, err := redisService.Get(cacheKey(username, window), &analytics)
+	if err != nil {
+		log.Warn("Failed to get from cache, proceeding without cache", "error", err)
+		// Continue without cache, don't fail
+	}
+
+	if ok && analytics != nil {
+		log.Info("Returning cached analytics")
+		return analytics, nil
+	}
+
+	if !window.End.IsZero() && window.End.Before(time.Now()) {
+		snap, ok, err := snapshots.Latest(username, window)
+		if err != nil {
+			log.Warn("Failed to read snapshots, proceeding without them", "error", err)
+		}
+		if ok && snap.Analytics != nil {
+			log.Info("Returning analytics from snapshot", "taken_at", snap.TakenAt)
+			return snap.Analytics, nil
+		}
+	}
+
+	return RefreshAnalytics(context.Background(), redisService, minioClient, snapshots, username, window)
+}
+
+// RefreshAnalytics computes the analytics for the window regardless of the
+// cache, then caches and snapshots them. Cancelling ctx stops the crawl and
+// nothing is cached.
+func RefreshAnalytics(ctx context.Context, redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store, username string, window analyzer.Window) (*analyzer.Analytics, error) {
+	log := logger.With("operation", "RefreshAnalytics", "username", username, "window", window.Key())
+
+	a, err := analyzer.NewAnalyzer(minioClient)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics, err := a.ProcessAnalytics(ctx, username, window)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the result (non-fatal if fails)
+	if err := redisService.Set(cacheKey(username, window), analytics, 48*time.Hour); err != nil {
+		log.Warn("Failed to cache analytics result", "error", err)
+	}
+
+	// Keep a dated snapshot for historical comparison (non-fatal if fails)
+	if _, err := snapshots.Save(username, analytics); err != nil {
+		log.Warn("Failed to save analytics snapshot", "error", err)
+	}
+
+	return analytics, nil
+}
+
+func AnalyticsHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store, jobs *webhook.Jobs) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		log := logger.With("handler", "AnalyticsHandler")
 
@@ -31,48 +134,261 @@ func AnalyticsHandler(redisService *storage.RedisService, minioClient *storage.M
 			return
 		}
 
-		log = logger.With("handler", "AnalyticsHandler", "username", anaReq.Username)
+		window, err := anaReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log = logger.With("handler", "AnalyticsHandler", "username", anaReq.Username, "window", window.Key())
+
+		if anaReq.CallbackURL != "" {
+			if err := webhook.ValidateCallbackURL(anaReq.CallbackURL); err != nil {
+				log.Warn("Invalid callback URL", "error", err)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			delivery, err := jobs.Submit(anaReq.CallbackURL, anaReq.Username, window, func() (*analyzer.Analytics, error) {
+				return LoadAnalytics(redisService, minioClient, snapshots, anaReq.Username, window)
+			})
+			if errors.Is(err, webhook.ErrQueueFull) {
+				ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				log.Error("Failed to record webhook delivery", "error", err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to accept analytics job",
+					"details": err.Error(),
+				})
+				return
+			}
+
+			log.Info("Analytics job accepted", "job_id", delivery.ID)
+			ctx.JSON(http.StatusAccepted, gin.H{
+				"job_id":   delivery.ID,
+				"status":   webhook.DeliveryPending,
+				"delivery": "/webhooks/deliveries/" + delivery.ID,
+			})
+			return
+		}
+
 		log.Info("Processing analytics request")
 
-		var analytics *analyzer.Analytics
-		ok, err := redisService.Get(anaReq.Username, &analytics)
+		analytics, err := LoadAnalytics(redisService, minioClient, snapshots, anaReq.Username, window)
 		if err != nil {
-			log.Warn("Failed to get from cache, proceeding without cache", "error", err)
-			// Continue without cache, don't fail
+			log.Error("Failed to process analytics", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process analytics",
+				"details": err.Error(),
+			})
+			return
 		}
 
-		if ok && analytics != nil {
-			log.Info("Returning cached analytics")
-			ctx.JSON(http.StatusOK, analytics)
+		log.Info("Analytics processed successfully")
+		ctx.JSON(http.StatusOK, analytics)
+	}
+}
+
+type DiffRequest struct {
+	Username string        `json:"username,omitempty"`
+	Base     WindowRequest `json:"base"`
+	Current  WindowRequest `json:"current"`
+}
+
+func DiffHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "DiffHandler")
+
+		var diffReq DiffRequest
+		if err := ctx.ShouldBindJSON(&diffReq); err != nil {
+			log.Warn("Invalid request body", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if diffReq.Username == "" {
+			log.Warn("Username is required")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
 			return
 		}
 
-		a, err := analyzer.NewAnalyzer(minioClient)
+		baseWindow, err := diffReq.Base.Window()
 		if err != nil {
-			log.Error("Failed to create analyzer", "error", err)
+			log.Warn("Invalid base window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		currentWindow, err := diffReq.Current.Window()
+		if err != nil {
+			log.Warn("Invalid current window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log = logger.With("handler", "DiffHandler",
+			"username", diffReq.Username,
+			"base_window", baseWindow.Key(),
+			"current_window", currentWindow.Key())
+		log.Info("Processing diff request")
+
+		base, err := LoadAnalytics(redisService, minioClient, snapshots, diffReq.Username, baseWindow)
+		if err != nil {
+			log.Error("Failed to process base analytics", "error", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to initialize analyzer",
+				"error":   "Failed to process base analytics",
 				"details": err.Error(),
 			})
 			return
 		}
 
-		analytics, err = a.ProcessAnalytics(anaReq.Username)
+		current, err := LoadAnalytics(redisService, minioClient, snapshots, diffReq.Username, currentWindow)
 		if err != nil {
-			log.Error("Failed to process analytics", "error", err)
+			log.Error("Failed to process current analytics", "error", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to process analytics",
+				"error":   "Failed to process current analytics",
 				"details": err.Error(),
 			})
 			return
 		}
 
-		// Cache the result (non-fatal if fails)
-		if err := redisService.Set(anaReq.Username, analytics, 48*time.Hour); err != nil {
-			log.Warn("Failed to cache analytics result", "error", err)
+		log.Info("Diff processed successfully")
+		ctx.JSON(http.StatusOK, analyzer.Compare(base, current))
+	}
+}
+
+func SnapshotsHandler(snapshots *snapshot.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.Param("username")
+		log := logger.With("handler", "SnapshotsHandler", "username", username)
+
+		list, err := snapshots.List(username)
+		if err != nil {
+			log.Error("Failed to list snapshots", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to list snapshots",
+				"details": err.Error(),
+			})
+			return
 		}
 
-		log.Info("Analytics processed successfully")
-		ctx.JSON(http.StatusOK, analytics)
+		ctx.JSON(http.StatusOK, gin.H{"username": username, "snapshots": list})
+	}
+}
+
+type CompareRequest struct {
+	Usernames []string `json:"usernames,omitempty"`
+	WindowRequest
+}
+
+type compareResult struct {
+	username  string
+	analytics *analyzer.Analytics
+	err       error
+}
+
+// loadAnalyticsConcurrently fans the usernames out to at most workers
+// goroutines, each reading the cache or computing the analytics.
+func loadAnalyticsConcurrently(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store, usernames []string, window analyzer.Window, workers int) []compareResult {
+	jobs := make(chan string)
+	results := make(chan compareResult, len(usernames))
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, len(usernames)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for username := range jobs {
+				a, err := LoadAnalytics(redisService, minioClient, snapshots, username, window)
+				results <- compareResult{username: username, analytics: a, err: err}
+			}
+		}()
+	}
+
+	for _, username := range usernames {
+		jobs <- username
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+
+	collected := make([]compareResult, 0, len(usernames))
+	for res := range results {
+		collected = append(collected, res)
+	}
+	return collected
+}
+
+func CompareHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store, workers int) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "CompareHandler")
+
+		var compareReq CompareRequest
+		if err := ctx.ShouldBindJSON(&compareReq); err != nil {
+			log.Warn("Invalid request body", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		usernames := uniqueUsernames(compareReq.Usernames)
+		if len(usernames) < 2 || len(usernames) > maxCompareChannels {
+			log.Warn("Invalid number of usernames", "count", len(usernames))
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("between 2 and %d distinct usernames are required", maxCompareChannels),
+			})
+			return
+		}
+
+		window, err := compareReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log = logger.With("handler", "CompareHandler", "usernames", usernames, "window", window.Key())
+		log.Info("Processing compare request", "workers", workers)
+
+		analytics := make(map[string]*analyzer.Analytics)
+		failures := make(map[string]string)
+		for _, res := range loadAnalyticsConcurrently(redisService, minioClient, snapshots, usernames, window, workers) {
+			if res.err != nil {
+				log.Warn("Failed to process channel analytics", "username", res.username, "error", res.err)
+				failures[res.username] = res.err.Error()
+				continue
+			}
+			analytics[res.username] = res.analytics
+		}
+
+		if len(analytics) == 0 {
+			log.Error("Failed to process analytics for every channel")
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process analytics",
+				"details": failures,
+			})
+			return
+		}
+
+		log.Info("Compare processed successfully", "failed", len(failures))
+		ctx.JSON(http.StatusOK, gin.H{
+			"comparison": analyzer.CompareChannels(window, analytics),
+			"errors":     failures,
+		})
+	}
+}
+
+func uniqueUsernames(usernames []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+		if username == "" || seen[strings.ToLower(username)] {
+			continue
+		}
+		seen[strings.ToLower(username)] = true
+		unique = append(unique, username)
 	}
+	return unique
 }
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/cards"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+// cardObjectName keys a card on the time the analytics were fetched, so a
+// refresh of the channel renders its cards again.
+func cardObjectName(username string, analytics *analyzer.Analytics, slide string) string {
+	return fmt.Sprintf("cards/%s/%s/%d/%s.png", strings.ToLower(username), analytics.Window.Key(), analytics.Info.CapturedAt.Unix(), slide)
+}
+
+// CardHandler serves one story card of a channel's wrap as a PNG, at
+// /cards/:channel/:slide.png. Cards are rendered on the first request for
+// the current analytics and stored in the bucket, later requests are served
+// from there.
+func CardHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := strings.TrimPrefix(ctx.Param("channel"), "@")
+		slide, isPNG := strings.CutSuffix(ctx.Param("slide"), ".png")
+		log := logger.With("handler", "CardHandler", "username", username, "slide", slide)
+
+		if !slices.Contains(cards.Slides, slide) {
+			log.Warn("Unknown card slide")
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "unknown slide", "slides": cards.Slides})
+			return
+		}
+		if !isPNG {
+			target := *ctx.Request.URL
+			target.Path += ".png"
+			ctx.Redirect(http.StatusMovedPermanently, target.String())
+			return
+		}
+
+		var windowReq WindowRequest
+		if err := ctx.ShouldBindQuery(&windowReq); err != nil {
+			log.Warn("Invalid query", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		window, err := windowReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log = log.With("window", window.Key())
+
+		analytics, err := LoadAnalytics(redisService, minioClient, snapshots, username, window)
+		if err != nil {
+			log.Error("Failed to process analytics", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process analytics",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		data, err := LoadCard(minioClient, username, analytics, slide)
+		if err != nil {
+			log.Error("Failed to render card", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to render card",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Info("Card served successfully")
+		ctx.Data(http.StatusOK, "image/png", data)
+	}
+}
+
+// LoadCard returns a story card of analytics, the one stored by an earlier
+// request for the same analytics when there is one, and renders and stores it
+// otherwise.
+func LoadCard(minioClient *storage.MinioClient, username string, analytics *analyzer.Analytics, slide string) ([]byte, error) {
+	log := logger.With("operation", "LoadCard", "username", username, "window", analytics.Window.Key(), "slide", slide)
+	objectName := cardObjectName(username, analytics, slide)
+
+	data, _, err := minioClient.GetObject(objectName)
+	switch {
+	case err == nil:
+		log.Debug("Serving stored card", "object", objectName)
+		return data, nil
+	case !errors.Is(err, apperrors.ErrObjectNotFound):
+		log.Warn("Failed to read stored card, rendering it again", "error", err)
+	}
+
+	data, err = cards.Render(analytics, slide, loadProfileImage(minioClient, analytics.ChannelProfile, log))
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the card for the next requests (non-fatal if fails)
+	if err := minioClient.UploadObject(objectName, data, "image/png"); err != nil {
+		log.Warn("Failed to store card", "error", err)
+	}
+	return data, nil
+}
+
+// profileObject returns the bucket object of a profile URL returned by the
+// analyzer, e.g. "channel-1234.jpg" for "/profiles/channel-1234.jpg".
+func profileObject(profile string) string {
+	return strings.TrimPrefix(profile, "/profiles/")
+}
+
+// loadProfileImage decodes the stored profile picture of a channel, nil when
+// it has none or it cannot be read, the cards then draw a placeholder.
+func loadProfileImage(minioClient *storage.MinioClient, profile string, log *slog.Logger) image.Image {
+	if profile == "" {
+		return nil
+	}
+	objectName := profileObject(profile)
+	data, _, err := minioClient.GetObject(objectName)
+	if err != nil {
+		log.Warn("Failed to read profile picture", "object", objectName, "error", err)
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Warn("Failed to decode profile picture", "object", objectName, "error", err)
+		return nil
+	}
+	return img
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/charts"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+// ChartRequest is read from the query string, the window fields are the same
+// as for the analytics.
+type ChartRequest struct {
+	WindowRequest
+	Theme string `form:"theme"`
+	Brand string `form:"brand"`
+}
+
+// ChartHandler serves a trend chart of a channel's wrap as an SVG, themed by
+// the theme and brand query parameters.
+func ChartHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := strings.TrimPrefix(ctx.Param("channel"), "@")
+		chart := strings.TrimSuffix(ctx.Param("chart"), ".svg")
+		log := logger.With("handler", "ChartHandler", "username", username, "chart", chart)
+
+		if !slices.Contains(charts.Charts, chart) {
+			log.Warn("Unknown chart")
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "unknown chart", "charts": charts.Charts})
+			return
+		}
+
+		var chartReq ChartRequest
+		if err := ctx.ShouldBindQuery(&chartReq); err != nil {
+			log.Warn("Invalid query", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		theme, err := charts.ParseTheme(chartReq.Theme, chartReq.Brand)
+		if err != nil {
+			log.Warn("Invalid chart theme", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		window, err := chartReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log = log.With("window", window.Key())
+
+		analytics, err := LoadAnalytics(redisService, minioClient, snapshots, username, window)
+		if err != nil {
+			log.Error("Failed to process analytics", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process analytics",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		data, err := charts.Render(analytics, chart, theme)
+		if err != nil {
+			log.Error("Failed to render chart", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to render chart",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		ctx.Data(http.StatusOK, "image/svg+xml", data)
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+const (
+	graphFormatJSON    = "json"
+	graphFormatGraphML = "graphml"
+	graphFormatDOT     = "dot"
+)
+
+type ForwardGraphRequest struct {
+	Username string `json:"username,omitempty"`
+	Format   string `json:"format,omitempty"`
+	WindowRequest
+}
+
+// ForwardGraphHandler exports the forward source graph of a channel as JSON
+// (default), GraphML or Graphviz DOT.
+func ForwardGraphHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "ForwardGraphHandler")
+
+		var graphReq ForwardGraphRequest
+		if err := ctx.ShouldBindJSON(&graphReq); err != nil {
+			log.Warn("Invalid request body", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if graphReq.Username == "" {
+			log.Warn("Username is required")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
+			return
+		}
+
+		format := graphReq.Format
+		if format == "" {
+			format = graphFormatJSON
+		}
+		if format != graphFormatJSON && format != graphFormatGraphML && format != graphFormatDOT {
+			log.Warn("Unsupported graph format", "format", format)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, graphml or dot"})
+			return
+		}
+
+		window, err := graphReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log = logger.With("handler", "ForwardGraphHandler", "username", graphReq.Username, "window", window.Key(), "format", format)
+		log.Info("Processing forward graph request")
+
+		analytics, err := LoadAnalytics(redisService, minioClient, snapshots, graphReq.Username, window)
+		if err != nil {
+			log.Error("Failed to process analytics", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to process analytics",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		var buf bytes.Buffer
+		contentType := ""
+		switch format {
+		case graphFormatJSON:
+			ctx.JSON(http.StatusOK, analytics.Forwards)
+			return
+		case graphFormatGraphML:
+			contentType = "application/graphml+xml"
+			err = analytics.Forwards.WriteGraphML(&buf)
+		case graphFormatDOT:
+			contentType = "text/vnd.graphviz"
+			err = analytics.Forwards.WriteDOT(&buf)
+		}
+		if err != nil {
+			log.Error("Failed to export forward graph", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export forward graph"})
+			return
+		}
+
+		log.Info("Forward graph exported successfully", "nodes", len(analytics.Forwards.Nodes))
+		ctx.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+const maxWatchlistChannels = 50
+
+type MentionsRequest struct {
+	Username  string   `json:"username,omitempty"`
+	Watchlist []string `json:"watchlist,omitempty"`
+	WindowRequest
+}
+
+func mentionsCacheKey(username string, watchlist []string, window analyzer.Window) string {
+	return fmt.Sprintf("mentions:%s:%s:%s", strings.ToLower(username), window.Key(), strings.Join(watchlist, ","))
+}
+
+// MentionsHandler scans a watchlist of channels for references to a channel.
+// Without a watchlist in the request the configured one is used.
+func MentionsHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, watchlist []string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "MentionsHandler")
+
+		var mentionsReq MentionsRequest
+		if err := ctx.ShouldBindJSON(&mentionsReq); err != nil {
+			log.Warn("Invalid request body", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if mentionsReq.Username == "" {
+			log.Warn("Username is required")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
+			return
+		}
+
+		watched := mentionsReq.Watchlist
+		if len(watched) == 0 {
+			watched = watchlist
+		}
+		watched = uniqueUsernames(watched)
+		sort.Strings(watched)
+		if len(watched) == 0 {
+			log.Warn("Watchlist is empty")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "watchlist is required when WATCHLIST_CHANNELS is not configured"})
+			return
+		}
+		if len(watched) > maxWatchlistChannels {
+			log.Warn("Too many watched channels", "count", len(watched))
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d watched channels are allowed", maxWatchlistChannels)})
+			return
+		}
+
+		window, err := mentionsReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log = logger.With("handler", "MentionsHandler", "username", mentionsReq.Username, "window", window.Key(), "watchlist", len(watched))
+		log.Info("Processing mentions request")
+
+		key := mentionsCacheKey(mentionsReq.Username, watched, window)
+		var report *analyzer.MentionReport
+		ok, err := redisService.Get(key, &report)
+		if err != nil {
+			log.Warn("Failed to get from cache, proceeding without cache", "error", err)
+		}
+		if ok && report != nil {
+			log.Info("Returning cached mentions")
+			ctx.JSON(http.StatusOK, report)
+			return
+		}
+
+		a, err := analyzer.NewAnalyzer(minioClient)
+		if err != nil {
+			log.Error("Failed to initialize analyzer", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize analyzer"})
+			return
+		}
+
+		report, err = a.DiscoverMentions(mentionsReq.Username, watched, window)
+		if err != nil {
+			log.Error("Failed to discover mentions", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to discover mentions",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if err := redisService.Set(key, report, 48*time.Hour); err != nil {
+			log.Warn("Failed to cache mentions result", "error", err)
+		}
+
+		log.Info("Mentions processed successfully", "referrers", len(report.Referrers))
+		ctx.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/charts"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/report"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+// ExportReport renders the wrap of a channel window as a self-contained HTML
+// report, with the profile pictures read from the bucket.
+func ExportReport(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store, username string, window analyzer.Window, theme charts.Theme) ([]byte, error) {
+	analytics, err := LoadAnalytics(redisService, minioClient, snapshots, username, window)
+	if err != nil {
+		return nil, err
+	}
+	return report.Render(analytics, report.Options{
+		Theme: theme,
+		Image: func(profile string) ([]byte, error) {
+			data, _, err := minioClient.GetObject(profileObject(profile))
+			return data, err
+		},
+	})
+}
+
+// ReportHandler serves the wrap of a channel as an HTML file to download,
+// themed by the same query parameters as the charts.
+func ReportHandler(redisService *storage.RedisService, minioClient *storage.MinioClient, snapshots *snapshot.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := strings.TrimSuffix(strings.TrimPrefix(ctx.Param("channel"), "@"), ".html")
+		log := logger.With("handler", "ReportHandler", "username", username)
+
+		var reportReq ChartRequest
+		if err := ctx.ShouldBindQuery(&reportReq); err != nil {
+			log.Warn("Invalid query", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		theme, err := charts.ParseTheme(reportReq.Theme, reportReq.Brand)
+		if err != nil {
+			log.Warn("Invalid report theme", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		window, err := reportReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log = log.With("window", window.Key())
+
+		data, err := ExportReport(redisService, minioClient, snapshots, username, window, theme)
+		if err != nil {
+			log.Error("Failed to export report", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to export report",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Info("Report exported successfully", "size", len(data))
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, report.Filename(username, window)))
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", data)
+	}
+}
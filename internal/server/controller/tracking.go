@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/scheduler"
+	"github.com/hunderaweke/tg-unwrapped/internal/tracking"
+)
+
+const defaultTrackingSchedule = "@daily"
+
+type TrackRequest struct {
+	Username string `json:"username,omitempty"`
+	Schedule string `json:"schedule,omitempty"`
+	WindowRequest
+}
+
+// TrackChannelHandler registers a channel whose analytics are refreshed on a
+// cron schedule, registering it again replaces its schedule and window.
+func TrackChannelHandler(tracker *tracking.Tracker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "TrackChannelHandler")
+
+		var trackReq TrackRequest
+		if err := ctx.ShouldBindJSON(&trackReq); err != nil {
+			log.Warn("Invalid request body", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if trackReq.Username == "" {
+			log.Warn("Username is required")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
+			return
+		}
+		if trackReq.Schedule == "" {
+			trackReq.Schedule = defaultTrackingSchedule
+		}
+
+		window, err := trackReq.Window()
+		if err != nil {
+			log.Warn("Invalid analysis window", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log = logger.With("handler", "TrackChannelHandler", "username", trackReq.Username, "schedule", trackReq.Schedule)
+
+		channel, err := tracker.Track(trackReq.Username, trackReq.Schedule, window)
+		if err != nil {
+			if errors.Is(err, scheduler.ErrInvalidCron) || errors.Is(err, tracking.ErrScheduleTooFrequent) {
+				log.Warn("Invalid schedule", "error", err)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Error("Failed to track channel", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to track channel",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Info("Channel tracked")
+		ctx.JSON(http.StatusCreated, channel)
+	}
+}
+
+func UntrackChannelHandler(tracker *tracking.Tracker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.Param("username")
+		log := logger.With("handler", "UntrackChannelHandler", "username", username)
+
+		if err := tracker.Untrack(username); err != nil {
+			log.Error("Failed to untrack channel", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to untrack channel",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Info("Channel untracked")
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// TrackedChannelsHandler lists the tracked channels with the outcome of their
+// last refresh and the time of the next one.
+func TrackedChannelsHandler(tracker *tracking.Tracker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "TrackedChannelsHandler")
+
+		channels, err := tracker.List()
+		if err != nil {
+			log.Error("Failed to list tracked channels", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to list tracked channels",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"channels": channels})
+	}
+}
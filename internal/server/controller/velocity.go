@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+	"github.com/hunderaweke/tg-unwrapped/internal/velocity"
+)
+
+// maxVelocityChannels bounds the registered channels, each one costs a few
+// Telegram calls on every sampling run.
+const maxVelocityChannels = 20
+
+type VelocityChannelRequest struct {
+	Username string `json:"username,omitempty"`
+}
+
+// RegisterVelocityChannelHandler adds a channel to the ones whose recent posts
+// are sampled in the background, once it resolves to a broadcast channel.
+func RegisterVelocityChannelHandler(store *velocity.Store, minioClient *storage.MinioClient) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "RegisterVelocityChannelHandler")
+
+		var channelReq VelocityChannelRequest
+		if err := ctx.ShouldBindJSON(&channelReq); err != nil {
+			log.Warn("Invalid request body", "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		channelReq.Username = strings.TrimPrefix(strings.TrimSpace(channelReq.Username), "@")
+		if channelReq.Username == "" {
+			log.Warn("Username is required")
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
+			return
+		}
+		log = log.With("username", channelReq.Username)
+
+		channels, err := store.Channels()
+		if err != nil {
+			log.Error("Failed to list channels", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to register channel",
+				"details": err.Error(),
+			})
+			return
+		}
+		registered := slices.Contains(channels, strings.ToLower(channelReq.Username))
+		if !registered && len(channels) >= maxVelocityChannels {
+			log.Warn("Too many channels registered", "channels", len(channels))
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "too many channels registered", "max_channels": maxVelocityChannels})
+			return
+		}
+
+		a, err := analyzer.NewAnalyzer(minioClient)
+		if err != nil {
+			log.Error("Failed to initialize analyzer", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize analyzer"})
+			return
+		}
+		if err := a.ValidateChannel(ctx.Request.Context(), channelReq.Username); err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrChannelNotFound):
+				log.Warn("Channel not found")
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+			case errors.Is(err, apperrors.ErrNotAChannel):
+				log.Warn("Not a broadcast channel")
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "only broadcast channels can be sampled"})
+			default:
+				log.Error("Failed to resolve channel", "error", err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to resolve channel",
+					"details": err.Error(),
+				})
+			}
+			return
+		}
+
+		if err := store.Register(channelReq.Username); err != nil {
+			log.Error("Failed to register channel", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to register channel",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Info("Channel registered for view sampling")
+		ctx.JSON(http.StatusCreated, gin.H{"username": channelReq.Username})
+	}
+}
+
+// UnregisterVelocityChannelHandler stops sampling a channel, the series
+// already stored stay available until they expire.
+func UnregisterVelocityChannelHandler(store *velocity.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.Param("username")
+		log := logger.With("handler", "UnregisterVelocityChannelHandler", "username", username)
+
+		if err := store.Unregister(username); err != nil {
+			log.Error("Failed to unregister channel", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to unregister channel",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Info("Channel unregistered from view sampling")
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+func VelocityChannelsHandler(store *velocity.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "VelocityChannelsHandler")
+
+		channels, err := store.Channels()
+		if err != nil {
+			log.Error("Failed to list channels", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to list channels",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"channels": channels})
+	}
+}
+
+// VelocityReportHandler reports the view velocity of the sampled posts of a
+// channel.
+func VelocityReportHandler(store *velocity.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.Param("username")
+		log := logger.With("handler", "VelocityReportHandler", "username", username)
+
+		series, err := store.Series(username)
+		if err != nil {
+			log.Error("Failed to load view series", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to load view series",
+				"details": err.Error(),
+			})
+			return
+		}
+		if len(series) == 0 {
+			log.Warn("No sampled posts")
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "no sampled posts, register the channel first"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, velocity.NewReport(username, series))
+	}
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/webhook"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+// DeliveryHandler returns the delivery log of an asynchronous analytics job,
+// the job ID is the one returned when it was accepted.
+func DeliveryHandler(store *webhook.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		log := logger.With("handler", "DeliveryHandler", "job_id", id)
+
+		delivery, ok, err := store.Get(id)
+		if err != nil {
+			log.Error("Failed to read delivery", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read delivery",
+				"details": err.Error(),
+			})
+			return
+		}
+		if !ok {
+			log.Warn("Delivery not found")
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, delivery)
+	}
+}
+
+// DeliveriesHandler lists the most recent deliveries, newest first, at most
+// limit of them.
+func DeliveriesHandler(store *webhook.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.With("handler", "DeliveriesHandler")
+
+		limit := defaultDeliveriesLimit
+		if raw := ctx.Query("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxDeliveriesLimit {
+				log.Warn("Invalid limit", "limit", raw)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxDeliveriesLimit)})
+				return
+			}
+			limit = n
+		}
+
+		deliveries, err := store.Recent(limit)
+		if err != nil {
+			log.Error("Failed to list deliveries", "error", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to list deliveries",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+	}
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/bot"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/server/controller"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
+)
+
+// RunBot serves wraps through the Telegram bot instead of the HTTP API, with
+// the same cache and snapshots.
+func RunBot() error {
+	env, logDir := initLogger()
+	defer logger.Close()
+
+	logger.Info("Starting TG-Wrapped bot", "env", env, "log_dir", logDir)
+
+	redisService, minioClient, err := initStorage()
+	if err != nil {
+		return err
+	}
+	snapshots := snapshot.NewStore(redisService)
+
+	b, err := bot.New(func(username string, window analyzer.Window) (*analyzer.Analytics, error) {
+		return controller.LoadAnalytics(redisService, minioClient, snapshots, username, window)
+	}, func(username string, window analyzer.Window, a *analyzer.Analytics, slide string) ([]byte, error) {
+		return controller.LoadCard(minioClient, username, a, slide)
+	})
+	if err != nil {
+		logger.Error("Failed to initialize bot", "error", err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := b.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	logger.Info("Bot stopped")
+	return nil
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/charts"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/report"
+	"github.com/hunderaweke/tg-unwrapped/internal/server/controller"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
+)
+
+// ExportOptions are the flags of the export mode, the window and theme are
+// read like the query of the report endpoint.
+type ExportOptions struct {
+	Channel string
+	controller.ChartRequest
+	// Output is the file written, named after the channel and window when
+	// empty
+	Output string
+}
+
+// RunExport writes the HTML report of a channel window to a file instead of
+// serving it, with the same cache and snapshots.
+func RunExport(opts ExportOptions) error {
+	env, logDir := initLogger()
+	defer logger.Close()
+
+	username := strings.TrimPrefix(opts.Channel, "@")
+	if username == "" {
+		return fmt.Errorf("%w: a channel is required to export a report", apperrors.ErrInvalidConfig)
+	}
+	theme, err := charts.ParseTheme(opts.Theme, opts.Brand)
+	if err != nil {
+		return err
+	}
+	window, err := opts.Window()
+	if err != nil {
+		return err
+	}
+
+	log := logger.With("operation", "RunExport", "username", username, "window", window.Key())
+	log.Info("Exporting TG-Wrapped report", "env", env, "log_dir", logDir)
+
+	redisService, minioClient, err := initStorage()
+	if err != nil {
+		return err
+	}
+	snapshots := snapshot.NewStore(redisService)
+
+	data, err := controller.ExportReport(redisService, minioClient, snapshots, username, window, theme)
+	if err != nil {
+		log.Error("Failed to export report", "error", err)
+		return err
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = report.Filename(username, window)
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		log.Error("Failed to write report", "error", err, "path", output)
+		return err
+	}
+	log.Info("Report exported successfully", "path", output, "size", len(data))
+	return nil
+}
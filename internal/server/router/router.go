@@ -1,22 +1,40 @@
 package router
 
 import (
+	"context"
+	"crypto/subtle"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
 	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/scheduler"
 	"github.com/hunderaweke/tg-unwrapped/internal/server/controller"
+	"github.com/hunderaweke/tg-unwrapped/internal/snapshot"
 	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+	"github.com/hunderaweke/tg-unwrapped/internal/tracking"
+	"github.com/hunderaweke/tg-unwrapped/internal/velocity"
+	"github.com/hunderaweke/tg-unwrapped/internal/webhook"
 )
 
-func Run() error {
-	// Initialize logger based on environment
-	env := os.Getenv("ENV")
-	logDir := os.Getenv("LOG_DIR")
+const (
+	defaultCompareWorkers         = 3
+	defaultVelocitySampleInterval = 10 * time.Minute
+	defaultWebhookBackoff         = 5 * time.Second
+	defaultWebhookWorkers         = 2
+	defaultWebhookQueueLimit      = 100
+)
+
+// initLogger sets up logging for the environment, the caller closes it.
+func initLogger() (env, logDir string) {
+	env = os.Getenv("ENV")
+	logDir = os.Getenv("LOG_DIR")
 	if logDir == "" {
 		logDir = "logs"
 	}
@@ -35,14 +53,14 @@ func Run() error {
 			logger.Warn("Failed to initialize file logging, using stdout only", "error", err)
 		}
 	}
-	defer logger.Close()
-
-	logger.Info("Starting TG-Wrapped server", "env", env, "log_dir", logDir)
+	return env, logDir
+}
 
+func initStorage() (*storage.RedisService, *storage.MinioClient, error) {
 	redisService, err := storage.NewRedis()
 	if err != nil {
 		logger.Error("Failed to initialize Redis", "error", err)
-		return err
+		return nil, nil, err
 	}
 
 	bucket := os.Getenv("MINIO_BUCKET")
@@ -54,9 +72,120 @@ func Run() error {
 	minioClient, err := storage.NewMinioBucket(bucket)
 	if err != nil {
 		logger.Error("Failed to initialize Minio", "error", err)
+		return nil, nil, err
+	}
+	return redisService, minioClient, nil
+}
+
+func Run() error {
+	env, logDir := initLogger()
+	defer logger.Close()
+
+	logger.Info("Starting TG-Wrapped server", "env", env, "log_dir", logDir)
+
+	redisService, minioClient, err := initStorage()
+	if err != nil {
 		return err
 	}
 
+	snapshots := snapshot.NewStore(redisService)
+
+	compareWorkers := defaultCompareWorkers
+	if workers := os.Getenv("COMPARE_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 1 {
+			logger.Warn("Invalid COMPARE_WORKERS, using default", "value", workers, "default", compareWorkers)
+		} else {
+			compareWorkers = n
+		}
+	}
+
+	watchlist := make([]string, 0)
+	if channels := os.Getenv("WATCHLIST_CHANNELS"); channels != "" {
+		watchlist = strings.Split(channels, ",")
+	}
+
+	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		logger.Warn("WEBHOOK_SECRET not set, webhooks are sent unsigned")
+	}
+	webhookAttempts := webhook.DefaultMaxAttempts
+	if attempts := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); attempts != "" {
+		n, err := strconv.Atoi(attempts)
+		if err != nil || n < 1 {
+			logger.Warn("Invalid WEBHOOK_MAX_ATTEMPTS, using default", "value", attempts, "default", webhookAttempts)
+		} else {
+			webhookAttempts = n
+		}
+	}
+	webhookWorkers := defaultWebhookWorkers
+	if workers := os.Getenv("WEBHOOK_WORKERS"); workers != "" {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 1 {
+			logger.Warn("Invalid WEBHOOK_WORKERS, using default", "value", workers, "default", webhookWorkers)
+		} else {
+			webhookWorkers = n
+		}
+	}
+	webhookQueueLimit := defaultWebhookQueueLimit
+	if limit := os.Getenv("WEBHOOK_QUEUE_LIMIT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			logger.Warn("Invalid WEBHOOK_QUEUE_LIMIT, using default", "value", limit, "default", webhookQueueLimit)
+		} else {
+			webhookQueueLimit = n
+		}
+	}
+	deliveries := webhook.NewStore(redisService)
+	dispatcher := webhook.NewDispatcher(deliveries, webhookSecret, webhookAttempts, defaultWebhookBackoff)
+	webhookJobs := webhook.NewJobs(dispatcher, webhookWorkers, webhookQueueLimit)
+
+	velocityStore := velocity.NewStore(redisService)
+
+	sampleInterval := defaultVelocitySampleInterval
+	if interval := os.Getenv("VELOCITY_SAMPLE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			logger.Warn("Invalid VELOCITY_SAMPLE_INTERVAL, using default", "value", interval, "default", sampleInterval)
+		} else {
+			sampleInterval = d
+		}
+	}
+
+	jobs := scheduler.New()
+	jobs.Add("velocity", scheduler.Every(sampleInterval), func(ctx context.Context) error {
+		a, err := analyzer.NewAnalyzer(minioClient)
+		if err != nil {
+			return err
+		}
+		return a.SampleViews(ctx, velocityStore)
+	})
+
+	// Tracked channels are refreshed through the same path as the requests,
+	// so the refresh lands in the cache they read
+	tracker := tracking.NewTracker(tracking.NewStore(redisService), jobs, func(ctx context.Context, username string, window analyzer.Window) error {
+		_, err := controller.RefreshAnalytics(ctx, redisService, minioClient, snapshots, username, window)
+		return err
+	})
+	if err := tracker.Load(); err != nil {
+		logger.Error("Failed to load tracked channels", "error", err)
+		return err
+	}
+
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminToken == "" {
+		logger.Warn("ADMIN_TOKEN not set, admin endpoints are disabled")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		jobs.Wait()
+		webhookJobs.Wait()
+	}()
+	jobs.Start(ctx)
+	webhookJobs.Start(ctx)
+
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(requestLogger())
@@ -68,7 +197,28 @@ func Run() error {
 	}
 
 	router.GET("/health", controller.HealthHandler)
-	router.POST("/analytics", controller.AnalyticsHandler(redisService, minioClient))
+	router.POST("/analytics", controller.AnalyticsHandler(redisService, minioClient, snapshots, webhookJobs))
+	router.POST("/analytics/diff", controller.DiffHandler(redisService, minioClient, snapshots))
+	router.POST("/analytics/compare", controller.CompareHandler(redisService, minioClient, snapshots, compareWorkers))
+	router.POST("/analytics/forwards", controller.ForwardGraphHandler(redisService, minioClient, snapshots))
+	router.POST("/analytics/mentions", adminAuth(adminToken), controller.MentionsHandler(redisService, minioClient, watchlist))
+	router.GET("/analytics/:username/snapshots", controller.SnapshotsHandler(snapshots))
+	router.GET("/velocity/channels", controller.VelocityChannelsHandler(velocityStore))
+	router.GET("/velocity/:username", controller.VelocityReportHandler(velocityStore))
+
+	router.GET("/cards/:channel/:slide", controller.CardHandler(redisService, minioClient, snapshots))
+	router.GET("/charts/:channel/:chart", controller.ChartHandler(redisService, minioClient, snapshots))
+	router.GET("/reports/:channel", controller.ReportHandler(redisService, minioClient, snapshots))
+	router.GET("/webhooks/deliveries/:id", controller.DeliveryHandler(deliveries))
+
+	admin := router.Group("/admin", adminAuth(adminToken))
+	admin.GET("/webhooks/deliveries", controller.DeliveriesHandler(deliveries))
+	admin.POST("/tracked", controller.TrackChannelHandler(tracker))
+	admin.GET("/tracked", controller.TrackedChannelsHandler(tracker))
+	admin.DELETE("/tracked/:username", controller.UntrackChannelHandler(tracker))
+	admin.POST("/velocity/channels", controller.RegisterVelocityChannelHandler(velocityStore, minioClient))
+	admin.DELETE("/velocity/channels/:username", controller.UnregisterVelocityChannelHandler(velocityStore))
+
 	router.GET("/profiles/:objectName", func(ctx *gin.Context) {
 		objectName := ctx.Param("objectName")
 		if objectName == "" {
@@ -109,3 +259,22 @@ func requestLogger() gin.HandlerFunc {
 			"client_ip", c.ClientIP())
 	}
 }
+
+// adminAuth requires the admin token as a bearer token. Without a configured
+// token every admin request is refused.
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			logger.Warn("Rejected admin request, ADMIN_TOKEN not set", "path", c.Request.URL.Path, "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin endpoints are disabled until ADMIN_TOKEN is set"})
+			return
+		}
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			logger.Warn("Rejected admin request", "path", c.Request.URL.Path, "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
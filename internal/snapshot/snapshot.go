@@ -0,0 +1,178 @@
+package snapshot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+const (
+	snapshotKeyPrefix = "snapshot"
+	indexKeyPrefix    = "snapshots"
+	// snapshots are kept until pruned, see Save
+	snapshotExpiry = 0
+	// maxSnapshots bounds the index of a channel, the oldest are dropped
+	maxSnapshots = 100
+)
+
+// Snapshot is a dated copy of the analytics computed for a channel window.
+type Snapshot struct {
+	Username  string              `json:"username"`
+	Window    analyzer.Window     `json:"window"`
+	TakenAt   time.Time           `json:"taken_at"`
+	Analytics *analyzer.Analytics `json:"analytics,omitempty"`
+}
+
+type Store struct {
+	redis *storage.RedisService
+}
+
+func NewStore(redisService *storage.RedisService) *Store {
+	return &Store{redis: redisService}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(username, "@"))
+}
+
+func indexKey(username string) string {
+	return fmt.Sprintf("%s:%s", indexKeyPrefix, normalizeUsername(username))
+}
+
+func windowPrefix(username string, window analyzer.Window) string {
+	return fmt.Sprintf("%s:%s:%s:", snapshotKeyPrefix, normalizeUsername(username), window.Key())
+}
+
+// Save persists a snapshot of a and records it in the channel's index. Only
+// the latest snapshot of a window is kept per UTC day, so frequent refreshes
+// replace each other, and a channel keeps at most maxSnapshots.
+func (s *Store) Save(username string, a *analyzer.Analytics) (Snapshot, error) {
+	log := logger.With("operation", "SaveSnapshot", "username", username, "window", a.Window.Key())
+
+	snap := Snapshot{
+		Username:  normalizeUsername(username),
+		Window:    a.Window,
+		TakenAt:   time.Now().UTC(),
+		Analytics: a,
+	}
+	// Nanoseconds keep two saves within the same second apart
+	key := windowPrefix(username, a.Window) + strconv.FormatInt(snap.TakenAt.UnixNano(), 10)
+	if err := s.redis.Set(key, snap, snapshotExpiry); err != nil {
+		log.Error("Failed to store snapshot", "error", err)
+		return Snapshot{}, err
+	}
+	if err := s.redis.AddToSortedSet(indexKey(username), key, float64(snap.TakenAt.Unix())); err != nil {
+		log.Error("Failed to index snapshot", "error", err)
+		return Snapshot{}, err
+	}
+
+	keys, err := s.redis.SortedSetMembers(indexKey(username))
+	if err != nil {
+		log.Warn("Failed to read snapshot index for pruning", "error", err)
+	}
+	for _, old := range prunable(keys, key, windowPrefix(username, a.Window)) {
+		if err := s.redis.Delete(old); err != nil {
+			log.Warn("Failed to delete pruned snapshot", "key", old, "error", err)
+			continue
+		}
+		if err := s.redis.RemoveFromSortedSet(indexKey(username), old); err != nil {
+			log.Warn("Failed to unindex pruned snapshot", "key", old, "error", err)
+		}
+	}
+
+	log.Info("Snapshot saved", "key", key)
+	return snap, nil
+}
+
+// takenAt reads the time a snapshot key was taken at. Older keys hold Unix
+// seconds, newer ones Unix nanoseconds.
+func takenAt(key string) (time.Time, bool) {
+	n, err := strconv.ParseInt(key[strings.LastIndex(key, ":")+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if n < 1e12 {
+		return time.Unix(n, 0).UTC(), true
+	}
+	return time.Unix(0, n).UTC(), true
+}
+
+// prunable returns the keys of the index, oldest first, to delete once latest
+// was saved: the snapshots of the same window taken the same UTC day, then the
+// oldest ones beyond maxSnapshots.
+func prunable(keys []string, latest, prefix string) []string {
+	day, ok := takenAt(latest)
+	if !ok {
+		return nil
+	}
+	day = day.Truncate(24 * time.Hour)
+
+	pruned := make([]string, 0)
+	kept := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != latest && strings.HasPrefix(key, prefix) {
+			if t, ok := takenAt(key); ok && t.Truncate(24*time.Hour).Equal(day) {
+				pruned = append(pruned, key)
+				continue
+			}
+		}
+		kept = append(kept, key)
+	}
+	if excess := len(kept) - maxSnapshots; excess > 0 {
+		pruned = append(pruned, kept[:excess]...)
+	}
+	return pruned
+}
+
+// List returns the snapshots of a channel, oldest first, without their
+// analytics payload.
+func (s *Store) List(username string) ([]Snapshot, error) {
+	keys, err := s.redis.SortedSetMembers(indexKey(username))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0, len(keys))
+	for _, key := range keys {
+		var snap Snapshot
+		ok, err := s.redis.Get(key, &snap)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		snap.Analytics = nil
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, nil
+}
+
+// Latest returns the most recent snapshot taken for the given window.
+func (s *Store) Latest(username string, window analyzer.Window) (*Snapshot, bool, error) {
+	keys, err := s.redis.SortedSetMembers(indexKey(username))
+	if err != nil {
+		return nil, false, err
+	}
+
+	prefix := windowPrefix(username, window)
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(keys[i], prefix) {
+			continue
+		}
+		var snap Snapshot
+		ok, err := s.redis.Get(keys[i], &snap)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return &snap, true, nil
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,39 @@
+package snapshot
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrunable(t *testing.T) {
+	day := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	key := func(window string, at time.Time) string {
+		return fmt.Sprintf("snapshot:channel:%s:%d", window, at.UnixNano())
+	}
+	prefix := "snapshot:channel:2025:"
+
+	yesterday := key("2025", day.Add(-time.Hour))
+	// Keys saved before nanosecond keys hold Unix seconds
+	morning := fmt.Sprintf("%s%d", prefix, day.Add(time.Hour).Unix())
+	noon := key("2025", day.Add(12*time.Hour))
+	otherWindow := key("2024", day.Add(13*time.Hour))
+	latest := key("2025", day.Add(23*time.Hour))
+
+	got := prunable([]string{yesterday, morning, noon, otherWindow, latest}, latest, prefix)
+	if want := []string{morning, noon}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("prunable = %v, want the earlier snapshots of the same window and day %v", got, want)
+	}
+
+	keys := make([]string, 0, maxSnapshots+2)
+	for i := range maxSnapshots + 2 {
+		keys = append(keys, key("2025", day.AddDate(0, 0, i-maxSnapshots-2)))
+	}
+	latest = key("2025", day)
+	keys = append(keys, latest)
+	got = prunable(keys, latest, prefix)
+	if want := keys[:3]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("prunable = %v, want the three oldest beyond %d snapshots", got, maxSnapshots)
+	}
+}
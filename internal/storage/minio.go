@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"time"
@@ -135,3 +136,55 @@ func (m *MinioClient) GenerateAccessURL(objectName string, expiryTime time.Durat
 	log.Debug("Generated access URL", "expiry", expiryTime)
 	return presignedURL.String(), nil
 }
+
+// UploadObject stores data under objectName, replacing any existing object.
+func (m *MinioClient) UploadObject(objectName string, data []byte, contentType string) error {
+	log := logger.With("operation", "UploadObject", "object", objectName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	_, err := m.Client.PutObject(ctx, m.BucketName, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		log.Error("Failed to upload object", "error", err)
+		return fmt.Errorf("%w: %v", apperrors.ErrUploadFailed, err)
+	}
+
+	log.Debug("Object uploaded successfully", "size", len(data), "content_type", contentType)
+	return nil
+}
+
+// GetObject reads a whole object along with its last modification time,
+// returning ErrObjectNotFound when it does not exist.
+func (m *MinioClient) GetObject(objectName string) ([]byte, time.Time, error) {
+	log := logger.With("operation", "GetObject", "object", objectName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	obj, err := m.Client.GetObject(ctx, m.BucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		log.Error("Failed to get object", "error", err)
+		return nil, time.Time{}, fmt.Errorf("%w: %v", apperrors.ErrMinioConnection, err)
+	}
+	defer obj.Close()
+
+	// The request is only sent on the first read or stat
+	info, err := obj.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, time.Time{}, apperrors.ErrObjectNotFound
+		}
+		log.Error("Failed to stat object", "error", err)
+		return nil, time.Time{}, fmt.Errorf("%w: %v", apperrors.ErrMinioConnection, err)
+	}
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		log.Error("Failed to read object", "error", err)
+		return nil, time.Time{}, fmt.Errorf("%w: %v", apperrors.ErrMinioConnection, err)
+	}
+	return data, info.LastModified, nil
+}
@@ -117,3 +117,53 @@ func (r *RedisService) Delete(key string) error {
 	log.Debug("Key deleted successfully")
 	return nil
 }
+
+func (r *RedisService) AddToSortedSet(key, member string, score float64) error {
+	log := logger.With("operation", "RedisZAdd", "key", key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	cmd := r.clnt.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+	if cmd.Err() != nil {
+		log.Error("Failed to add sorted set member", "error", cmd.Err())
+		return cmd.Err()
+	}
+
+	log.Debug("Sorted set member added", "member", member)
+	return nil
+}
+
+func (r *RedisService) RemoveFromSortedSet(key, member string) error {
+	log := logger.With("operation", "RedisZRem", "key", key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	cmd := r.clnt.ZRem(ctx, key, member)
+	if cmd.Err() != nil {
+		log.Error("Failed to remove sorted set member", "error", cmd.Err())
+		return cmd.Err()
+	}
+
+	log.Debug("Sorted set member removed", "member", member)
+	return nil
+}
+
+// SortedSetMembers returns every member of the sorted set ordered by score,
+// lowest first.
+func (r *RedisService) SortedSetMembers(key string) ([]string, error) {
+	log := logger.With("operation", "RedisZRange", "key", key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	members, err := r.clnt.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		log.Error("Failed to read sorted set", "error", err)
+		return nil, err
+	}
+
+	log.Debug("Sorted set read successfully", "members", len(members))
+	return members, nil
+}
@@ -0,0 +1,39 @@
+package topics
+
+// stopWords are the function words of the channel languages (English,
+// Amharic and Afaan Oromo) plus words common to any announcement. Words
+// shorter than minTermLength are already skipped.
+var stopWords = toSet(
+	// English
+	"the", "and", "for", "are", "but", "not", "you", "all", "any", "can",
+	"had", "her", "was", "one", "our", "out", "has", "have", "his", "how",
+	"its", "may", "new", "now", "see", "who", "did", "get", "let", "she",
+	"too", "use", "this", "that", "with", "from", "they", "will", "would",
+	"there", "their", "what", "about", "which", "when", "your", "were",
+	"been", "than", "then", "them", "these", "those", "into", "more", "some",
+	"such", "only", "also", "over", "very", "just", "like", "here", "after",
+	"before", "because", "while", "where", "should", "could", "being", "does",
+	"each", "other", "most", "much", "many", "well", "even", "still", "both",
+	"between", "through", "during", "without", "under", "again", "today",
+	"said", "says", "join", "follow", "channel", "subscribe", "please",
+	"https", "http", "www",
+	// Amharic
+	"እና", "ነው", "ናቸው", "ነበር", "ላይ", "ውስጥ", "ጋር", "ግን", "ወደ", "እንደ",
+	"ይህ", "ይህን", "ያለው", "የሆነ", "ሲሆን", "ነገር", "ብቻ", "ደግሞ", "ማለት",
+	"እስከ", "በኋላ", "በፊት", "ምክንያት", "እንዲሁም", "አንድ", "ሁሉ", "ሁሉም",
+	"እነዚህ", "እኛ", "እርስዎ", "አሁን", "ዛሬ", "ተብሏል", "ገልጸዋል", "አስታውቋል",
+	// Afaan Oromo
+	"fi", "kan", "kana", "sana", "akka", "irra", "irratti", "keessa",
+	"keessatti", "waliin", "garuu", "yookaan", "ykn", "isaa", "isaanii",
+	"isaan", "keenya", "keessan", "jira", "jiru", "ture", "turan", "dha",
+	"ta'e", "ni", "hin", "hunda", "tokko", "amma", "har'a", "waan", "itti",
+	"irraa", "gara", "booda", "dura", "kanaaf", "jedhe", "jedhan",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
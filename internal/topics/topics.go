@@ -0,0 +1,321 @@
+// Package topics groups short texts into topics offline. Documents are turned
+// into TF-IDF vectors and clustered with k-means, each cluster being labeled
+// by the heaviest terms of its centroid.
+package topics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxVocabulary  = 2000
+	maxIterations  = 25
+	labelTerms     = 3
+	topTermLimit   = 8
+	samplePostSize = 3
+	minTermLength  = 3
+	// seed keeps the clustering of the same posts stable between runs.
+	seed = 42
+)
+
+type Document struct {
+	ID         int
+	Text       string
+	Views      int
+	Engagement int
+}
+
+type Topic struct {
+	Label                 string   `json:"label"`
+	Terms                 []string `json:"terms"`
+	Posts                 int      `json:"posts"`
+	Share                 float64  `json:"share"`
+	TotalViews            int      `json:"total_views"`
+	AverageViews          float64  `json:"average_views"`
+	AverageEngagementRate float64  `json:"average_engagement_rate"`
+	SamplePostIDs         []int    `json:"sample_post_ids"`
+}
+
+type vector map[int]float64
+
+// Cluster groups docs into at most k topics, picking k from the number of
+// documents when k is 0. Documents without any vocabulary term are left out.
+// Topics are returned largest first.
+func Cluster(docs []Document, k int) []Topic {
+	tokens := make([][]string, len(docs))
+	for i, doc := range docs {
+		tokens[i] = Tokenize(doc.Text)
+	}
+	vocab := buildVocabulary(tokens)
+	if len(vocab.terms) == 0 {
+		return make([]Topic, 0)
+	}
+
+	vectors := make([]vector, 0, len(docs))
+	kept := make([]Document, 0, len(docs))
+	for i, doc := range docs {
+		v := tfidf(tokens[i], vocab)
+		if len(v) == 0 {
+			continue
+		}
+		vectors = append(vectors, v)
+		kept = append(kept, doc)
+	}
+	if len(vectors) == 0 {
+		return make([]Topic, 0)
+	}
+
+	if k <= 0 {
+		k = int(math.Round(math.Sqrt(float64(len(vectors)) / 2)))
+		k = max(2, min(k, 8))
+	}
+	k = min(k, len(vectors))
+
+	assignments, centroids := kmeans(vectors, len(vocab.terms), k)
+	return summarize(kept, assignments, centroids, vocab)
+}
+
+// Tokenize lowercases text and keeps the words worth clustering on: no stop
+// words, numbers, links or words shorter than minTermLength.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r) && r != '/' && r != '.' && r != '#' && r != '@'
+	})
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		// Links, domains and mentions say little about the topic
+		word = strings.Trim(word, ".#")
+		if strings.ContainsAny(word, "/.#") || strings.HasPrefix(word, "@") {
+			continue
+		}
+		if utf8.RuneCountInString(word) < minTermLength || stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+type vocabulary struct {
+	index map[string]int
+	terms []string
+	idf   []float64
+}
+
+// buildVocabulary keeps the terms found in at least two documents but not in
+// more than half of them, the most widespread first.
+func buildVocabulary(tokens [][]string) vocabulary {
+	df := make(map[string]int)
+	for _, doc := range tokens {
+		seen := make(map[string]bool)
+		for _, token := range doc {
+			if !seen[token] {
+				seen[token] = true
+				df[token]++
+			}
+		}
+	}
+
+	n := len(tokens)
+	terms := make([]string, 0, len(df))
+	for term, cnt := range df {
+		if cnt >= 2 && (n < 4 || cnt*2 <= n) {
+			terms = append(terms, term)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if df[terms[i]] == df[terms[j]] {
+			return terms[i] < terms[j]
+		}
+		return df[terms[i]] > df[terms[j]]
+	})
+	if len(terms) > maxVocabulary {
+		terms = terms[:maxVocabulary]
+	}
+
+	v := vocabulary{index: make(map[string]int), terms: terms, idf: make([]float64, len(terms))}
+	for i, term := range terms {
+		v.index[term] = i
+		v.idf[i] = math.Log(float64(n)/float64(df[term])) + 1
+	}
+	return v
+}
+
+// tfidf returns the L2 normalized TF-IDF vector of a tokenized document.
+func tfidf(tokens []string, v vocabulary) vector {
+	tf := make(vector)
+	for _, token := range tokens {
+		if i, ok := v.index[token]; ok {
+			tf[i]++
+		}
+	}
+	norm := 0.0
+	for i, cnt := range tf {
+		tf[i] = cnt * v.idf[i]
+		norm += tf[i] * tf[i]
+	}
+	norm = math.Sqrt(norm)
+	for i := range tf {
+		tf[i] /= norm
+	}
+	return tf
+}
+
+func dot(v vector, centroid []float64) float64 {
+	sum := 0.0
+	for i, weight := range v {
+		sum += weight * centroid[i]
+	}
+	return sum
+}
+
+// kmeans clusters unit vectors by cosine similarity (spherical k-means) with
+// k-means++ seeding.
+func kmeans(vectors []vector, dims, k int) ([]int, [][]float64) {
+	rng := rand.New(rand.NewSource(seed))
+
+	centroids := make([][]float64, 0, k)
+	toCentroid := func(v vector) []float64 {
+		c := make([]float64, dims)
+		for i, weight := range v {
+			c[i] = weight
+		}
+		return c
+	}
+	centroids = append(centroids, toCentroid(vectors[rng.Intn(len(vectors))]))
+	distances := make([]float64, len(vectors))
+	for len(centroids) < k {
+		total := 0.0
+		for i, v := range vectors {
+			best := math.Inf(1)
+			for _, c := range centroids {
+				best = math.Min(best, 1-dot(v, c))
+			}
+			distances[i] = best * best
+			total += distances[i]
+		}
+		if total == 0 {
+			break
+		}
+		target := rng.Float64() * total
+		chosen := len(vectors) - 1
+		for i, d := range distances {
+			target -= d
+			if target <= 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, toCentroid(vectors[chosen]))
+	}
+
+	assignments := make([]int, len(vectors))
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := false
+		for i, v := range vectors {
+			best, bestSimilarity := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if similarity := dot(v, centroid); similarity > bestSimilarity {
+					best, bestSimilarity = c, similarity
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+		if !changed && iteration > 0 {
+			break
+		}
+
+		for c := range centroids {
+			centroid := make([]float64, dims)
+			members := 0
+			for i, v := range vectors {
+				if assignments[i] != c {
+					continue
+				}
+				members++
+				for j, weight := range v {
+					centroid[j] += weight
+				}
+			}
+			if members == 0 {
+				continue
+			}
+			norm := 0.0
+			for _, weight := range centroid {
+				norm += weight * weight
+			}
+			norm = math.Sqrt(norm)
+			for j := range centroid {
+				centroid[j] /= norm
+			}
+			centroids[c] = centroid
+		}
+	}
+	return assignments, centroids
+}
+
+func summarize(docs []Document, assignments []int, centroids [][]float64, v vocabulary) []Topic {
+	topics := make([]Topic, len(centroids))
+	rates := make([]float64, len(centroids))
+	rated := make([]int, len(centroids))
+	for i, doc := range docs {
+		t := &topics[assignments[i]]
+		t.Posts++
+		t.TotalViews += doc.Views
+		if doc.Views != 0 {
+			rates[assignments[i]] += float64(doc.Engagement) / float64(doc.Views)
+			rated[assignments[i]]++
+		}
+		if len(t.SamplePostIDs) < samplePostSize {
+			t.SamplePostIDs = append(t.SamplePostIDs, doc.ID)
+		}
+	}
+
+	result := make([]Topic, 0, len(topics))
+	for c, t := range topics {
+		if t.Posts == 0 {
+			continue
+		}
+		t.Terms = topTerms(centroids[c], v.terms, topTermLimit)
+		t.Label = strings.Join(t.Terms[:min(labelTerms, len(t.Terms))], ", ")
+		t.Share = float64(t.Posts) / float64(len(docs))
+		t.AverageViews = float64(t.TotalViews) / float64(t.Posts)
+		if rated[c] != 0 {
+			t.AverageEngagementRate = rates[c] / float64(rated[c])
+		}
+		result = append(result, t)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Posts > result[j].Posts })
+	return result
+}
+
+func topTerms(centroid []float64, terms []string, limit int) []string {
+	indexes := make([]int, 0, len(centroid))
+	for i, weight := range centroid {
+		if weight > 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		if centroid[indexes[i]] == centroid[indexes[j]] {
+			return terms[indexes[i]] < terms[indexes[j]]
+		}
+		return centroid[indexes[i]] > centroid[indexes[j]]
+	})
+	if len(indexes) > limit {
+		indexes = indexes[:limit]
+	}
+	top := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		top = append(top, terms[i])
+	}
+	return top
+}
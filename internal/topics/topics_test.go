@@ -0,0 +1,43 @@
+package topics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCluster(t *testing.T) {
+	docs := []Document{
+		{ID: 1, Text: "Football match tonight, the team won the league final", Views: 100, Engagement: 10},
+		{ID: 2, Text: "League football: our team plays the final match", Views: 200, Engagement: 30},
+		{ID: 3, Text: "Football fans celebrate as the team lifts the league trophy", Views: 150, Engagement: 15},
+		{ID: 4, Text: "Inflation rises as the central bank raises interest rates", Views: 80, Engagement: 4},
+		{ID: 5, Text: "The central bank warns inflation will hurt interest on savings", Views: 90, Engagement: 9},
+		{ID: 6, Text: "Bank interest rates and inflation dominate the budget debate", Views: 70, Engagement: 7},
+	}
+
+	topics := Cluster(docs, 2)
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want 2: %+v", len(topics), topics)
+	}
+	for _, topic := range topics {
+		if topic.Posts != 3 {
+			t.Fatalf("topic %q has %d posts, want 3", topic.Label, topic.Posts)
+		}
+		sports := strings.Contains(topic.Label, "football") || strings.Contains(topic.Label, "league") || strings.Contains(topic.Label, "team")
+		finance := strings.Contains(topic.Label, "inflation") || strings.Contains(topic.Label, "bank") || strings.Contains(topic.Label, "interest")
+		if sports == finance {
+			t.Fatalf("topic label %q mixes or misses both themes", topic.Label)
+		}
+	}
+
+	if got := Cluster(nil, 0); len(got) != 0 {
+		t.Fatalf("clustering no documents returned %v", got)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := strings.Join(Tokenize("Read more at https://t.me/news and example.com @admin #Breaking news!"), " ")
+	if got != "read breaking news" {
+		t.Fatalf("Tokenize = %q, want %q", got, "read breaking news")
+	}
+}
@@ -0,0 +1,91 @@
+package tracking
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+const (
+	channelsKey      = "tracked:channels"
+	channelKeyPrefix = "tracked:channel"
+	// tracked channels are kept until untracked
+	channelExpiry = 0
+)
+
+// Channel is a channel whose analytics are refreshed on a schedule. Username
+// is kept as registered, it is part of the analytics cache key.
+type Channel struct {
+	Username string          `json:"username"`
+	Schedule string          `json:"schedule"`
+	Window   analyzer.Window `json:"window"`
+	AddedAt  time.Time       `json:"added_at"`
+
+	LastRefreshedAt *time.Time `json:"last_refreshed_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	NextRefreshAt   *time.Time `json:"next_refresh_at,omitempty"`
+}
+
+type Store struct {
+	redis *storage.RedisService
+}
+
+func NewStore(redisService *storage.RedisService) *Store {
+	return &Store{redis: redisService}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+func channelKey(username string) string {
+	return fmt.Sprintf("%s:%s", channelKeyPrefix, normalizeUsername(username))
+}
+
+func (s *Store) Save(c Channel) error {
+	c.NextRefreshAt = nil
+	if err := s.redis.Set(channelKey(c.Username), c, channelExpiry); err != nil {
+		return err
+	}
+	return s.redis.AddToSortedSet(channelsKey, normalizeUsername(c.Username), float64(c.AddedAt.Unix()))
+}
+
+func (s *Store) Get(username string) (*Channel, bool, error) {
+	var c Channel
+	ok, err := s.redis.Get(channelKey(username), &c)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return &c, true, nil
+}
+
+func (s *Store) Delete(username string) error {
+	if err := s.redis.Delete(channelKey(username)); err != nil {
+		return err
+	}
+	return s.redis.RemoveFromSortedSet(channelsKey, normalizeUsername(username))
+}
+
+// List returns the tracked channels, oldest first.
+func (s *Store) List() ([]Channel, error) {
+	usernames, err := s.redis.SortedSetMembers(channelsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]Channel, 0, len(usernames))
+	for _, username := range usernames {
+		c, ok, err := s.Get(username)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		channels = append(channels, *c)
+	}
+	return channels, nil
+}
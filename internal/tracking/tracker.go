@@ -0,0 +1,165 @@
+// Package tracking refreshes the analytics of registered channels in the
+// background, so requests for them are served from the cache.
+package tracking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/scheduler"
+)
+
+// minRefreshInterval is the shortest gap allowed between two refreshes of a
+// channel, each one being a full crawl and a snapshot.
+const minRefreshInterval = time.Hour
+
+var ErrScheduleTooFrequent = errors.New("schedule refreshes more often than once an hour")
+
+// RefreshFunc recomputes the analytics of a channel window, caches and
+// snapshots them.
+type RefreshFunc func(ctx context.Context, username string, window analyzer.Window) error
+
+// Tracker keeps one scheduler job per tracked channel.
+type Tracker struct {
+	store   *Store
+	jobs    *scheduler.Scheduler
+	refresh RefreshFunc
+	// refreshing serializes the refreshes, they share the Telegram account
+	// and its rate limit with the requests
+	refreshing sync.Mutex
+}
+
+func NewTracker(store *Store, jobs *scheduler.Scheduler, refresh RefreshFunc) *Tracker {
+	return &Tracker{store: store, jobs: jobs, refresh: refresh}
+}
+
+func jobName(username string) string {
+	return "track:" + normalizeUsername(username)
+}
+
+// Load schedules the channels already tracked, it runs once at startup.
+func (t *Tracker) Load() error {
+	channels, err := t.store.List()
+	if err != nil {
+		return err
+	}
+	for _, c := range channels {
+		schedule, err := parseSchedule(c.Schedule)
+		if err != nil {
+			logger.Warn("Tracked channel has an invalid schedule, skipping", "username", c.Username, "schedule", c.Schedule, "error", err)
+			continue
+		}
+		t.jobs.Add(jobName(c.Username), schedule, t.job(c.Username))
+	}
+	logger.Info("Tracked channels loaded", "channels", len(channels))
+	return nil
+}
+
+// parseSchedule parses a cron expression, see scheduler.ParseCron, and
+// rejects it when two runs within a week are closer than minRefreshInterval.
+func parseSchedule(spec string) (scheduler.Schedule, error) {
+	schedule, err := scheduler.ParseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// A week covers every day of week and hour combination
+	from := time.Now().UTC()
+	prev := schedule.Next(from)
+	for !prev.IsZero() && prev.Before(from.AddDate(0, 0, 7)) {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(prev); gap < minRefreshInterval {
+			return nil, fmt.Errorf("%w: %q runs %s apart", ErrScheduleTooFrequent, spec, gap)
+		}
+		prev = next
+	}
+	return schedule, nil
+}
+
+// Track registers or reschedules a channel. The schedule is a cron
+// expression refreshing at most once an hour, see scheduler.ParseCron.
+func (t *Tracker) Track(username, spec string, window analyzer.Window) (*Channel, error) {
+	schedule, err := parseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Channel{
+		Username: strings.TrimSpace(username),
+		Schedule: spec,
+		Window:   window,
+		AddedAt:  time.Now().UTC(),
+	}
+	if existing, ok, err := t.store.Get(username); err != nil {
+		return nil, err
+	} else if ok {
+		c.AddedAt = existing.AddedAt
+	}
+	if err := t.store.Save(c); err != nil {
+		return nil, err
+	}
+
+	t.jobs.Add(jobName(username), schedule, t.job(c.Username))
+	next := schedule.Next(time.Now())
+	c.NextRefreshAt = &next
+	return &c, nil
+}
+
+// Untrack stops refreshing a channel, its cached analytics and snapshots are
+// kept.
+func (t *Tracker) Untrack(username string) error {
+	t.jobs.Remove(jobName(username))
+	return t.store.Delete(username)
+}
+
+// List returns the tracked channels with their next refresh time.
+func (t *Tracker) List() ([]Channel, error) {
+	channels, err := t.store.List()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range channels {
+		if schedule, err := scheduler.ParseCron(channels[i].Schedule); err == nil {
+			if next := schedule.Next(now); !next.IsZero() {
+				channels[i].NextRefreshAt = &next
+			}
+		}
+	}
+	return channels, nil
+}
+
+func (t *Tracker) job(username string) scheduler.Job {
+	return func(ctx context.Context) error {
+		t.refreshing.Lock()
+		defer t.refreshing.Unlock()
+
+		// The channel is read on every run so a reschedule or untrack made
+		// while waiting for the lock is honored
+		c, ok, err := t.store.Get(username)
+		if err != nil || !ok || ctx.Err() != nil {
+			return err
+		}
+
+		refreshErr := t.refresh(ctx, c.Username, c.Window)
+		now := time.Now().UTC()
+		c.LastRefreshedAt = &now
+		c.LastError = ""
+		if refreshErr != nil {
+			c.LastError = refreshErr.Error()
+		}
+		if err := t.store.Save(*c); err != nil {
+			logger.Warn("Failed to record refresh of tracked channel", "username", username, "error", err)
+		}
+		return refreshErr
+	}
+}
@@ -0,0 +1,36 @@
+package tracking
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/scheduler"
+)
+
+func TestParseSchedule(t *testing.T) {
+	cases := []struct {
+		spec string
+		want error
+	}{
+		{"@hourly", nil},
+		{"@daily", nil},
+		{"0 */6 * * *", nil},
+		{"@every 1h", nil},
+		{"@every 1m", ErrScheduleTooFrequent},
+		{"*/30 * * * *", ErrScheduleTooFrequent},
+		// Weekly, but with two runs five minutes apart
+		{"0,5 9 * * 1", ErrScheduleTooFrequent},
+		{"0 0 30 2 *", nil},
+		{"not a schedule", scheduler.ErrInvalidCron},
+	}
+
+	for _, tc := range cases {
+		_, err := parseSchedule(tc.spec)
+		if tc.want == nil && err != nil {
+			t.Fatalf("%q: unexpected error %v", tc.spec, err)
+		}
+		if tc.want != nil && !errors.Is(err, tc.want) {
+			t.Fatalf("%q: expected %v, got %v", tc.spec, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package velocity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+const (
+	channelsKey     = "velocity:channels"
+	seriesKeyPrefix = "velocity"
+	// seriesExpiry keeps finished series around for reports after tracking
+	seriesExpiry = 30 * 24 * time.Hour
+)
+
+// Store keeps the channels registered for sampling and their post series in
+// Redis.
+type Store struct {
+	redis *storage.RedisService
+}
+
+func NewStore(redisService *storage.RedisService) *Store {
+	return &Store{redis: redisService}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+func indexKey(username string) string {
+	return fmt.Sprintf("%s:%s:posts", seriesKeyPrefix, normalizeUsername(username))
+}
+
+func seriesKey(username string, postID int) string {
+	return fmt.Sprintf("%s:%s:post:%d", seriesKeyPrefix, normalizeUsername(username), postID)
+}
+
+func (s *Store) Register(username string) error {
+	return s.redis.AddToSortedSet(channelsKey, normalizeUsername(username), float64(time.Now().Unix()))
+}
+
+func (s *Store) Unregister(username string) error {
+	return s.redis.RemoveFromSortedSet(channelsKey, normalizeUsername(username))
+}
+
+// Channels returns the registered channels, oldest registration first.
+func (s *Store) Channels() ([]string, error) {
+	return s.redis.SortedSetMembers(channelsKey)
+}
+
+func (s *Store) Save(username string, series PostSeries) error {
+	key := seriesKey(username, series.PostID)
+	if err := s.redis.Set(key, series, seriesExpiry); err != nil {
+		return err
+	}
+	return s.redis.AddToSortedSet(indexKey(username), key, float64(series.PublishedAt.Unix()))
+}
+
+// Series returns the stored series of a channel, oldest post first. Expired
+// series are dropped from the index on the way.
+func (s *Store) Series(username string) ([]PostSeries, error) {
+	log := logger.With("operation", "VelocitySeries", "username", username)
+
+	keys, err := s.redis.SortedSetMembers(indexKey(username))
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]PostSeries, 0, len(keys))
+	for _, key := range keys {
+		var p PostSeries
+		ok, err := s.redis.Get(key, &p)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if err := s.redis.RemoveFromSortedSet(indexKey(username), key); err != nil {
+				log.Warn("Failed to drop expired series from index", "key", key, "error", err)
+			}
+			continue
+		}
+		series = append(series, p)
+	}
+	sort.SliceStable(series, func(i, j int) bool { return series[i].PublishedAt.Before(series[j].PublishedAt) })
+	return series, nil
+}
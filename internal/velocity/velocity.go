@@ -0,0 +1,161 @@
+// Package velocity keeps the view counts of recent posts sampled at fixed ages
+// after publication, Telegram itself only exposes the current count.
+package velocity
+
+import (
+	"time"
+)
+
+// Checkpoint is a post age at which views are sampled.
+type Checkpoint struct {
+	Name string
+	Age  time.Duration
+}
+
+var Checkpoints = []Checkpoint{
+	{Name: "1h", Age: time.Hour},
+	{Name: "6h", Age: 6 * time.Hour},
+	{Name: "24h", Age: 24 * time.Hour},
+	{Name: "72h", Age: 72 * time.Hour},
+}
+
+// TrackingPeriod is how long after publication a post is sampled.
+var TrackingPeriod = Checkpoints[len(Checkpoints)-1].Age
+
+type Sample struct {
+	Checkpoint string    `json:"checkpoint"`
+	TakenAt    time.Time `json:"taken_at"`
+	AgeHours   float64   `json:"age_hours"`
+	Views      int       `json:"views"`
+}
+
+// PostSeries is the views time series of one post.
+type PostSeries struct {
+	PostID      int       `json:"post_id"`
+	PublishedAt time.Time `json:"published_at"`
+	Samples     []Sample  `json:"samples"`
+}
+
+func (p PostSeries) sampled(checkpoint string) (Sample, bool) {
+	for _, s := range p.Samples {
+		if s.Checkpoint == checkpoint {
+			return s, true
+		}
+	}
+	return Sample{}, false
+}
+
+// Due returns the checkpoint to sample at now: the latest one the post has
+// reached, when it was not sampled yet. Checkpoints missed entirely, e.g.
+// while the server was down, stay empty rather than holding late counts.
+func (p PostSeries) Due(now time.Time) (Checkpoint, bool) {
+	age := now.Sub(p.PublishedAt)
+	for i := len(Checkpoints) - 1; i >= 0; i-- {
+		if age < Checkpoints[i].Age {
+			continue
+		}
+		if _, ok := p.sampled(Checkpoints[i].Name); ok {
+			return Checkpoint{}, false
+		}
+		return Checkpoints[i], true
+	}
+	return Checkpoint{}, false
+}
+
+// Record adds the views sampled at now for checkpoint.
+func (p *PostSeries) Record(checkpoint Checkpoint, now time.Time, views int) {
+	p.Samples = append(p.Samples, Sample{
+		Checkpoint: checkpoint.Name,
+		TakenAt:    now.UTC(),
+		AgeHours:   now.Sub(p.PublishedAt).Hours(),
+		Views:      views,
+	})
+}
+
+type CurvePoint struct {
+	Checkpoint   string  `json:"checkpoint"`
+	Posts        int     `json:"posts"`
+	AverageViews float64 `json:"average_views"`
+	// ShareOfFinal is the average share of the 72h views already reached at
+	// the checkpoint, over the posts sampled at both.
+	ShareOfFinal float64 `json:"share_of_final"`
+}
+
+type GrowthPost struct {
+	PostID       int       `json:"post_id"`
+	PublishedAt  time.Time `json:"published_at"`
+	ViewsPerHour float64   `json:"views_per_hour"`
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+}
+
+type Report struct {
+	Username          string       `json:"username"`
+	TrackedPosts      int          `json:"tracked_posts"`
+	AverageViewsAt24h float64      `json:"average_views_at_24h"`
+	DecayCurve        []CurvePoint `json:"decay_curve"`
+	FastestGrowing    *GrowthPost  `json:"fastest_growing,omitempty"`
+	Posts             []PostSeries `json:"posts"`
+}
+
+// NewReport summarizes the sampled series of a channel. The fastest growing
+// post gained the most views per hour between two consecutive samples, or
+// between publication and its first sample.
+func NewReport(username string, series []PostSeries) Report {
+	report := Report{
+		Username:     username,
+		TrackedPosts: len(series),
+		DecayCurve:   make([]CurvePoint, 0, len(Checkpoints)),
+		Posts:        series,
+	}
+	final := Checkpoints[len(Checkpoints)-1].Name
+
+	for _, checkpoint := range Checkpoints {
+		point := CurvePoint{Checkpoint: checkpoint.Name}
+		views, shares, shared := 0, 0.0, 0
+		for _, p := range series {
+			sample, ok := p.sampled(checkpoint.Name)
+			if !ok {
+				continue
+			}
+			point.Posts++
+			views += sample.Views
+			if last, ok := p.sampled(final); ok && last.Views != 0 {
+				shares += float64(sample.Views) / float64(last.Views)
+				shared++
+			}
+		}
+		if point.Posts != 0 {
+			point.AverageViews = float64(views) / float64(point.Posts)
+		}
+		if shared != 0 {
+			point.ShareOfFinal = shares / float64(shared)
+		}
+		if checkpoint.Name == "24h" {
+			report.AverageViewsAt24h = point.AverageViews
+		}
+		report.DecayCurve = append(report.DecayCurve, point)
+	}
+
+	for _, p := range series {
+		previous := Sample{Checkpoint: "published"}
+		for _, s := range p.Samples {
+			hours := s.AgeHours - previous.AgeHours
+			if hours <= 0 {
+				continue
+			}
+			rate := float64(s.Views-previous.Views) / hours
+			if report.FastestGrowing == nil || rate > report.FastestGrowing.ViewsPerHour {
+				report.FastestGrowing = &GrowthPost{
+					PostID:       p.PostID,
+					PublishedAt:  p.PublishedAt,
+					ViewsPerHour: rate,
+					From:         previous.Checkpoint,
+					To:           s.Checkpoint,
+				}
+			}
+			previous = s
+		}
+	}
+	return report
+}
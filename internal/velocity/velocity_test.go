@@ -0,0 +1,65 @@
+package velocity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDue(t *testing.T) {
+	published := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := PostSeries{PostID: 1, PublishedAt: published}
+
+	if _, ok := p.Due(published.Add(30 * time.Minute)); ok {
+		t.Fatalf("expected nothing due before the first checkpoint")
+	}
+
+	// A run after 7h skips the missed 1h checkpoint
+	checkpoint, ok := p.Due(published.Add(7 * time.Hour))
+	if !ok || checkpoint.Name != "6h" {
+		t.Fatalf("expected 6h due, got %q (%v)", checkpoint.Name, ok)
+	}
+	p.Record(checkpoint, published.Add(7*time.Hour), 100)
+
+	if _, ok := p.Due(published.Add(8 * time.Hour)); ok {
+		t.Fatalf("expected nothing due once 6h was sampled")
+	}
+	if checkpoint, ok := p.Due(published.Add(25 * time.Hour)); !ok || checkpoint.Name != "24h" {
+		t.Fatalf("expected 24h due, got %q (%v)", checkpoint.Name, ok)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	published := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := []PostSeries{
+		{PostID: 1, PublishedAt: published, Samples: []Sample{
+			{Checkpoint: "1h", AgeHours: 1, Views: 100},
+			{Checkpoint: "24h", AgeHours: 24, Views: 400},
+			{Checkpoint: "72h", AgeHours: 72, Views: 500},
+		}},
+		{PostID: 2, PublishedAt: published, Samples: []Sample{
+			{Checkpoint: "1h", AgeHours: 1, Views: 300},
+			{Checkpoint: "24h", AgeHours: 24, Views: 600},
+		}},
+	}
+
+	report := NewReport("test", series)
+	if report.TrackedPosts != 2 {
+		t.Fatalf("expected 2 tracked posts, got %d", report.TrackedPosts)
+	}
+	if report.AverageViewsAt24h != 500 {
+		t.Fatalf("expected 500 average views at 24h, got %v", report.AverageViewsAt24h)
+	}
+	if len(report.DecayCurve) != len(Checkpoints) {
+		t.Fatalf("expected %d curve points, got %d", len(Checkpoints), len(report.DecayCurve))
+	}
+	// Only post 1 reached 72h, 100 of its 500 views came in the first hour
+	if share := report.DecayCurve[0].ShareOfFinal; share != 0.2 {
+		t.Fatalf("expected 1h share of 0.2, got %v", share)
+	}
+	if report.DecayCurve[1].Posts != 0 {
+		t.Fatalf("expected no posts sampled at 6h, got %d", report.DecayCurve[1].Posts)
+	}
+	if report.FastestGrowing == nil || report.FastestGrowing.PostID != 2 || report.FastestGrowing.ViewsPerHour != 300 {
+		t.Fatalf("expected post 2 growing at 300 views/hour, got %+v", report.FastestGrowing)
+	}
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	requestTimeout     = 15 * time.Second
+	dialTimeout        = 5 * time.Second
+	// responseBodyLimit bounds what is read from the receiver, only the
+	// status code matters
+	responseBodyLimit = 4 << 10
+)
+
+// deliveryLog is where the dispatcher records deliveries, the Store in
+// production.
+type deliveryLog interface {
+	Save(d Delivery) error
+	Get(id string) (*Delivery, bool, error)
+}
+
+// Dispatcher posts payloads to callback URLs, retrying failed attempts with
+// exponential backoff, and logs every attempt in the store.
+type Dispatcher struct {
+	store       deliveryLog
+	client      *http.Client
+	secret      []byte
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewDispatcher signs payloads with secret, an empty secret sends them
+// unsigned. The first retry waits backoff, every later one twice as long.
+func NewDispatcher(store *Store, secret string, maxAttempts int, backoff time.Duration) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      newClient(),
+		secret:      []byte(secret),
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+// newClient returns a client that only connects to public addresses. The
+// check runs on the resolved address of every connection, redirects
+// included, so a host name cannot point the callbacks at internal services.
+// Callbacks do not go through the environment proxy for the same reason.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublic(addr.Addr()) {
+				return fmt.Errorf("%w: %s is not a public address", ErrInvalidCallbackURL, addr.Addr())
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: dialTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// Accept records a pending delivery for a job that was just accepted, so it
+// can be queried before the job completes.
+func (d *Dispatcher) Accept(jobID, callbackURL, username string) (Delivery, error) {
+	delivery := Delivery{
+		ID:        jobID,
+		URL:       callbackURL,
+		Username:  username,
+		Status:    DeliveryPending,
+		Attempts:  make([]Attempt, 0),
+		CreatedAt: time.Now().UTC(),
+	}
+	return delivery, d.store.Save(delivery)
+}
+
+// retryable reports whether a failed attempt may succeed later: network
+// errors, timeouts, rate limiting and server errors.
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+// Deliver posts the payload of an accepted job until the receiver answers
+// with a 2xx status, the error is not retryable or the attempts run out.
+func (d *Dispatcher) Deliver(ctx context.Context, payload Payload) {
+	log := logger.With("operation", "DeliverWebhook", "job_id", payload.JobID, "username", payload.Username)
+
+	delivery, ok, err := d.store.Get(payload.JobID)
+	if err != nil || !ok {
+		log.Error("Delivery not found, dropping webhook", "error", err)
+		return
+	}
+	delivery.JobStatus = payload.Status
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Error("Failed to encode webhook payload", "error", err)
+		d.finish(delivery, DeliveryFailed, log)
+		return
+	}
+
+	wait := d.backoff
+	for number := 1; number <= d.maxAttempts; number++ {
+		attempt, err := d.post(ctx, delivery, body, number)
+		delivery.Attempts = append(delivery.Attempts, attempt)
+
+		if attempt.Error == "" {
+			log.Info("Webhook delivered", "attempt", number, "status_code", attempt.StatusCode)
+			d.finish(delivery, DeliveryDelivered, log)
+			return
+		}
+		log.Warn("Webhook attempt failed", "attempt", number, "status_code", attempt.StatusCode, "error", attempt.Error)
+		// A blocked address stays blocked, it is not worth retrying
+		if !retryable(attempt.StatusCode) || errors.Is(err, ErrInvalidCallbackURL) || number == d.maxAttempts {
+			break
+		}
+
+		if err := d.store.Save(*delivery); err != nil {
+			log.Warn("Failed to log webhook attempt", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Warn("Webhook retries cancelled", "error", ctx.Err())
+			d.finish(delivery, DeliveryFailed, log)
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+
+	log.Error("Webhook delivery failed", "attempts", len(delivery.Attempts))
+	d.finish(delivery, DeliveryFailed, log)
+}
+
+// post makes one attempt, the error is the one recorded in the attempt.
+func (d *Dispatcher) post(ctx context.Context, delivery *Delivery, body []byte, number int) (attempt Attempt, err error) {
+	attempt = Attempt{Number: number, At: time.Now().UTC()}
+	defer func() {
+		attempt.Duration = time.Since(attempt.At)
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return attempt, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TG-Wrapped-Webhook")
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(attempt.At.Unix(), 10))
+	if len(d.secret) != 0 {
+		req.Header.Set(SignatureHeader, Sign(d.secret, attempt.At, body))
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return attempt, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, responseBodyLimit))
+
+	attempt.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return attempt, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return attempt, nil
+}
+
+func (d *Dispatcher) finish(delivery *Delivery, status string, log *slog.Logger) {
+	now := time.Now().UTC()
+	delivery.Status = status
+	delivery.CompletedAt = &now
+	if err := d.store.Save(*delivery); err != nil {
+		log.Warn("Failed to log webhook delivery", "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+)
+
+type memoryLog struct {
+	mu         sync.Mutex
+	deliveries map[string]Delivery
+}
+
+func (m *memoryLog) Save(d Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d.Attempts = append([]Attempt(nil), d.Attempts...)
+	m.deliveries[d.ID] = d
+	return nil
+}
+
+func (m *memoryLog) Get(id string) (*Delivery, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &d, true, nil
+}
+
+// deliver posts one payload to a receiver answering with the given statuses
+// in turn, and returns the logged delivery and the number of requests made.
+func deliver(t *testing.T, client func(*httptest.Server) *http.Client, backoff time.Duration, statuses ...int) (Delivery, int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[min(requests, len(statuses)-1)]
+		requests++
+		mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if !Verify([]byte("secret"), time.Unix(timestamp, 0), body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("expected every attempt to be signed")
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	log := &memoryLog{deliveries: make(map[string]Delivery)}
+	d := &Dispatcher{
+		store:       log,
+		client:      client(srv),
+		secret:      []byte("secret"),
+		maxAttempts: 4,
+		backoff:     backoff,
+	}
+	if _, err := d.Accept("job", srv.URL, "channel"); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	d.Deliver(context.Background(), NewPayload("job", "channel", analyzer.DefaultWindow(), &analyzer.Analytics{}, nil))
+
+	delivery, ok, _ := log.Get("job")
+	if !ok {
+		t.Fatalf("expected the delivery to be logged")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return *delivery, requests
+}
+
+func testClient(srv *httptest.Server) *http.Client {
+	return srv.Client()
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	backoff := 20 * time.Millisecond
+	delivery, requests := deliver(t, testClient, backoff, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+
+	if requests != 3 || delivery.Status != DeliveryDelivered || delivery.JobStatus != StatusCompleted {
+		t.Fatalf("expected delivery on the third request, got %d requests and %+v", requests, delivery)
+	}
+	if len(delivery.Attempts) != 3 || delivery.CompletedAt == nil {
+		t.Fatalf("expected 3 logged attempts and a completion time, got %+v", delivery)
+	}
+	for i, want := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK} {
+		attempt := delivery.Attempts[i]
+		if attempt.Number != i+1 || attempt.StatusCode != want || (attempt.Error == "") != (want == http.StatusOK) {
+			t.Fatalf("attempt %d = %+v, want status %d", i+1, attempt, want)
+		}
+	}
+	if gap := delivery.Attempts[1].At.Sub(delivery.Attempts[0].At); gap < backoff {
+		t.Fatalf("expected the first retry after %v, got %v", backoff, gap)
+	}
+	if gap := delivery.Attempts[2].At.Sub(delivery.Attempts[1].At); gap < 2*backoff {
+		t.Fatalf("expected the backoff to double to %v, got %v", 2*backoff, gap)
+	}
+}
+
+func TestDeliverDoesNotRetryClientErrors(t *testing.T) {
+	delivery, requests := deliver(t, testClient, time.Millisecond, http.StatusBadRequest, http.StatusOK)
+
+	if requests != 1 || delivery.Status != DeliveryFailed || len(delivery.Attempts) != 1 {
+		t.Fatalf("expected a single failed attempt, got %d requests and %+v", requests, delivery)
+	}
+	if attempt := delivery.Attempts[0]; attempt.StatusCode != http.StatusBadRequest || attempt.Error == "" {
+		t.Fatalf("expected the 400 to be logged, got %+v", attempt)
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	delivery, requests := deliver(t, testClient, time.Millisecond, http.StatusInternalServerError)
+
+	if requests != 4 || delivery.Status != DeliveryFailed || len(delivery.Attempts) != 4 {
+		t.Fatalf("expected 4 failed attempts, got %d requests and %+v", requests, delivery)
+	}
+}
+
+func TestDeliverRefusesPrivateAddresses(t *testing.T) {
+	delivery, requests := deliver(t, func(*httptest.Server) *http.Client { return newClient() }, time.Millisecond, http.StatusOK)
+
+	if requests != 0 || delivery.Status != DeliveryFailed || len(delivery.Attempts) != 1 {
+		t.Fatalf("expected a single refused attempt, got %d requests and %+v", requests, delivery)
+	}
+	if !strings.Contains(delivery.Attempts[0].Error, "not a public address") {
+		t.Fatalf("expected the loopback address to be refused, got %q", delivery.Attempts[0].Error)
+	}
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	"github.com/hunderaweke/tg-unwrapped/internal/logger"
+)
+
+var ErrQueueFull = errors.New("analytics job queue is full")
+
+// Job computes the analytics of an asynchronous request.
+type Job func() (*analyzer.Analytics, error)
+
+type queuedJob struct {
+	id       string
+	username string
+	window   analyzer.Window
+	run      Job
+}
+
+// Jobs runs asynchronous analytics jobs on a fixed number of workers, with a
+// bounded number waiting, and delivers their outcome to the callback URL.
+type Jobs struct {
+	dispatcher *Dispatcher
+	workers    int
+	queue      chan queuedJob
+	wg         sync.WaitGroup
+}
+
+func NewJobs(dispatcher *Dispatcher, workers, limit int) *Jobs {
+	return &Jobs{
+		dispatcher: dispatcher,
+		workers:    workers,
+		queue:      make(chan queuedJob, limit),
+	}
+}
+
+// Start runs the workers until ctx is done, jobs still waiting then stay
+// pending.
+func (j *Jobs) Start(ctx context.Context) {
+	for i := 0; i < j.workers; i++ {
+		j.wg.Add(1)
+		go func() {
+			defer j.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-j.queue:
+					j.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until every worker returned after ctx is done.
+func (j *Jobs) Wait() {
+	j.wg.Wait()
+}
+
+// Submit accepts a job whose outcome is posted to callbackURL. When the queue
+// is full the delivery is recorded as failed and ErrQueueFull is returned.
+func (j *Jobs) Submit(callbackURL, username string, window analyzer.Window, run Job) (Delivery, error) {
+	id := NewJobID()
+	delivery, err := j.dispatcher.Accept(id, callbackURL, username)
+	if err != nil {
+		return delivery, err
+	}
+
+	select {
+	case j.queue <- queuedJob{id: id, username: username, window: window, run: run}:
+		return delivery, nil
+	default:
+		log := logger.With("operation", "SubmitJob", "job_id", id, "username", username)
+		log.Warn("Analytics job queue is full, rejecting job", "limit", cap(j.queue))
+		j.dispatcher.finish(&delivery, DeliveryFailed, log)
+		return delivery, ErrQueueFull
+	}
+}
+
+func (j *Jobs) process(ctx context.Context, job queuedJob) {
+	log := logger.With("operation", "ProcessJob", "job_id", job.id, "username", job.username, "window", job.window.Key())
+
+	analytics, err := job.run()
+	if err != nil {
+		log.Error("Failed to process analytics job", "error", err)
+	}
+	j.dispatcher.Deliver(ctx, NewPayload(job.id, job.username, job.window, analytics, err))
+}
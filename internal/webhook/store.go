@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/storage"
+)
+
+const (
+	deliveriesKey     = "webhook:deliveries"
+	deliveryKeyPrefix = "webhook:delivery"
+	// deliveryExpiry bounds how long delivery logs can be queried
+	deliveryExpiry = 7 * 24 * time.Hour
+
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Attempt is one POST to the callback URL.
+type Attempt struct {
+	Number     int           `json:"number"`
+	At         time.Time     `json:"at"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Delivery is the log of the attempts to post the outcome of one job.
+type Delivery struct {
+	ID          string     `json:"id"`
+	URL         string     `json:"url"`
+	Username    string     `json:"username"`
+	JobStatus   string     `json:"job_status,omitempty"`
+	Status      string     `json:"status"`
+	Attempts    []Attempt  `json:"attempts"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type Store struct {
+	redis *storage.RedisService
+}
+
+func NewStore(redisService *storage.RedisService) *Store {
+	return &Store{redis: redisService}
+}
+
+func deliveryKey(id string) string {
+	return fmt.Sprintf("%s:%s", deliveryKeyPrefix, id)
+}
+
+func (s *Store) Save(d Delivery) error {
+	if err := s.redis.Set(deliveryKey(d.ID), d, deliveryExpiry); err != nil {
+		return err
+	}
+	return s.redis.AddToSortedSet(deliveriesKey, d.ID, float64(d.CreatedAt.Unix()))
+}
+
+func (s *Store) Get(id string) (*Delivery, bool, error) {
+	var d Delivery
+	ok, err := s.redis.Get(deliveryKey(id), &d)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return &d, true, nil
+}
+
+// Recent returns up to limit deliveries, newest first. Expired deliveries are
+// dropped from the index on the way.
+func (s *Store) Recent(limit int) ([]Delivery, error) {
+	ids, err := s.redis.SortedSetMembers(deliveriesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]Delivery, 0, min(limit, len(ids)))
+	for i := len(ids) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		d, ok, err := s.Get(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if err := s.redis.RemoveFromSortedSet(deliveriesKey, ids[i]); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,129 @@
+// Package webhook posts the outcome of analytics jobs to the callback URL of
+// the request, signed and retried, and keeps a log of every delivery.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+)
+
+const (
+	SignatureHeader = "X-TG-Wrapped-Signature"
+	TimestampHeader = "X-TG-Wrapped-Timestamp"
+	DeliveryHeader  = "X-TG-Wrapped-Delivery"
+
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+var ErrInvalidCallbackURL = errors.New("invalid callback URL")
+
+// Failure describes why a job failed, Op is the failed analyzer operation
+// when the error came from the analyzer.
+type Failure struct {
+	Op      string `json:"op,omitempty"`
+	Message string `json:"message"`
+}
+
+// Payload is the body posted to the callback URL.
+type Payload struct {
+	JobID       string              `json:"job_id"`
+	Username    string              `json:"username"`
+	Window      analyzer.Window     `json:"window"`
+	Status      string              `json:"status"`
+	CompletedAt time.Time           `json:"completed_at"`
+	Analytics   *analyzer.Analytics `json:"analytics,omitempty"`
+	Error       *Failure            `json:"error,omitempty"`
+}
+
+// NewPayload wraps the outcome of a job, the analytics on success or the
+// failure envelope otherwise.
+func NewPayload(jobID, username string, window analyzer.Window, analytics *analyzer.Analytics, err error) Payload {
+	p := Payload{
+		JobID:       jobID,
+		Username:    username,
+		Window:      window,
+		Status:      StatusCompleted,
+		CompletedAt: time.Now().UTC(),
+		Analytics:   analytics,
+	}
+	if err != nil {
+		p.Status = StatusFailed
+		p.Analytics = nil
+		p.Error = &Failure{Message: err.Error()}
+		var analyzerErr *apperrors.AnalyzerError
+		if errors.As(err, &analyzerErr) {
+			p.Error.Op = analyzerErr.Op
+			p.Error.Message = analyzerErr.Err.Error()
+		}
+	}
+	return p
+}
+
+// NewJobID returns a random identifier for an asynchronous job, it is also
+// the ID of its delivery.
+func NewJobID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidateCallbackURL accepts absolute http and https URLs to public hosts.
+// Host names are resolved when posting, the dialer then rejects the private
+// addresses they may resolve to.
+func ValidateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q must be an absolute http(s) URL", ErrInvalidCallbackURL, raw)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %q points to the local host", ErrInvalidCallbackURL, raw)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return fmt.Errorf("%w: %q points to a loopback, private or link-local address", ErrInvalidCallbackURL, raw)
+	}
+	return nil
+}
+
+// isPublic reports whether callbacks may be posted to addr, internal services
+// must not be reachable through them.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsUnspecified() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast()
+}
+
+// Sign returns the signature header value for a body sent at timestamp: the
+// hex HMAC-SHA256, keyed with the secret, of "<timestamp>.<body>". Receivers
+// recompute it and reject old timestamps to prevent replays.
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign.
+func Verify(secret []byte, timestamp time.Time, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hunderaweke/tg-unwrapped/internal/analyzer"
+	apperrors "github.com/hunderaweke/tg-unwrapped/internal/errors"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("secret")
+	at := time.Unix(1735689600, 0)
+	body := []byte(`{"job_id":"1"}`)
+
+	signature := Sign(secret, at, body)
+	if !Verify(secret, at, body, signature) {
+		t.Fatalf("expected signature %q to verify", signature)
+	}
+	if Verify(secret, at.Add(time.Second), body, signature) {
+		t.Fatalf("expected a different timestamp to fail")
+	}
+	if Verify([]byte("other"), at, body, signature) {
+		t.Fatalf("expected a different secret to fail")
+	}
+	if Verify(secret, at, []byte(`{"job_id":"2"}`), signature) {
+		t.Fatalf("expected a different body to fail")
+	}
+}
+
+func TestNewPayloadFailure(t *testing.T) {
+	err := apperrors.NewAnalyzerError("resolve_username", "missing", apperrors.ErrChannelNotFound)
+	p := NewPayload("job", "missing", analyzer.DefaultWindow(), &analyzer.Analytics{}, err)
+
+	if p.Status != StatusFailed || p.Analytics != nil {
+		t.Fatalf("expected a failed payload without analytics, got %+v", p)
+	}
+	if p.Error == nil || p.Error.Op != "resolve_username" || p.Error.Message != apperrors.ErrChannelNotFound.Error() {
+		t.Fatalf("expected the analyzer op and message, got %+v", p.Error)
+	}
+
+	p = NewPayload("job", "channel", analyzer.DefaultWindow(), nil, errors.New("boom"))
+	if p.Error == nil || p.Error.Op != "" || p.Error.Message != "boom" {
+		t.Fatalf("expected a plain failure, got %+v", p.Error)
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	for _, raw := range []string{"https://example.com/hook", "http://203.0.113.7:8080/cb", "https://[2001:db8::1]/cb"} {
+		if err := ValidateCallbackURL(raw); err != nil {
+			t.Fatalf("%q: unexpected error %v", raw, err)
+		}
+	}
+	for _, raw := range []string{
+		"", "example.com/hook", "ftp://example.com", "/relative",
+		"http://localhost:8080/cb", "http://api.localhost/cb", "http://127.0.0.1:6379",
+		"http://[::1]/cb", "http://10.0.0.5/cb", "http://192.168.1.10/cb", "http://172.16.0.1/cb",
+		"http://169.254.169.254/latest/meta-data", "http://0.0.0.0:9000", "http://[::ffff:127.0.0.1]/cb",
+	} {
+		if err := ValidateCallbackURL(raw); !errors.Is(err, ErrInvalidCallbackURL) {
+			t.Fatalf("%q: expected ErrInvalidCallbackURL, got %v", raw, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"os"
 
 	"github.com/hunderaweke/tg-unwrapped/internal/logger"
@@ -9,8 +10,32 @@ import (
 )
 
 func main() {
-	if err := router.Run(); err != nil {
-		logger.Error("Server failed", "error", err)
+	mode := flag.String("mode", "server", "run the HTTP API (server), the Telegram bot (bot) or export an HTML report (export)")
+	var export router.ExportOptions
+	flag.StringVar(&export.Channel, "channel", "", "channel to export, in export mode")
+	flag.IntVar(&export.Year, "year", 0, "calendar year to export, in export mode")
+	flag.StringVar(&export.From, "from", "", "start date (YYYY-MM-DD) to export from, in export mode")
+	flag.StringVar(&export.To, "to", "", "end date (YYYY-MM-DD) to export to, in export mode")
+	flag.StringVar(&export.Timezone, "timezone", "", "IANA timezone of the window, in export mode")
+	flag.StringVar(&export.Theme, "theme", "", "report theme, light or dark, in export mode")
+	flag.StringVar(&export.Brand, "brand", "", "report brand color as hex, in export mode")
+	flag.StringVar(&export.Output, "out", "", "file to write the report to, named after the channel and window by default")
+	flag.Parse()
+
+	run := router.Run
+	switch *mode {
+	case "server":
+	case "bot":
+		run = router.RunBot
+	case "export":
+		run = func() error { return router.RunExport(export) }
+	default:
+		logger.Error("Unknown mode", "mode", *mode)
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		logger.Error("Run failed", "error", err, "mode", *mode)
 		os.Exit(1)
 	}
 }